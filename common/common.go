@@ -2,7 +2,6 @@ package common
 
 import (
 	"fmt"
-	"github.com/dromara/carbon/v2"
 	"github.com/fatih/color"
 	"os/exec"
 	"runtime"
@@ -28,7 +27,7 @@ func Head() {
 
 // PrintDividingLine 打印分割线
 func PrintDividingLine() {
-	fmt.Println("\n" + DividingLine + "\n")
+	fmt.Print("\n" + DividingLine + "\n\n")
 }
 
 // PrintError 打印错误
@@ -37,11 +36,6 @@ func PrintError(content string) {
 	color.New(color.FgRed).Add(color.Bold).Println(content + "\n")
 }
 
-// FormatDate 格式化日期
-func FormatDate(time string) string {
-	return carbon.Parse(time).Layout("20060102_150405")
-}
-
 // minDateTime 返回时间字符串中的最小值，忽略空字符串
 func minDateTime(times []string) (string, error) {
 	var minTime *time.Time
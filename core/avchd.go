@@ -0,0 +1,267 @@
+package core
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"time"
+
+	"github.com/thoas/go-funk"
+)
+
+// avchdScanLimit 最多读取的文件长度,MDPM随每个I帧写入,通常位于文件开头
+const avchdScanLimit = 8 << 20
+
+// avchdDefaultVideoPID 找不到PMT时使用AVCHD默认的视频PID
+const avchdDefaultVideoPID = 0x1011
+
+// avchdMDPMUUID H.264 SEI user_data_unregistered中MDPM数据的UUID
+var avchdMDPMUUID = []byte{0x17, 0xee, 0x8c, 0x60, 0xf8, 0x4d, 0x11, 0xd9, 0x8c, 0xd6, 0x08, 0x00, 0x20, 0x0c, 0x9a, 0x66}
+
+// MDPM中记录拍摄时间的标签
+const (
+	avchdTagDate = 0x18 // 时区、年(2字节)、月
+	avchdTagTime = 0x19 // 日、时、分、秒
+)
+
+// errNotAVCHD 文件不是AVCHD格式
+var errNotAVCHD = errors.New("不是有效的MTS/M2TS文件")
+
+// IsAVCHD 判断文件是否为AVCHD(MTS/M2TS)格式的视频
+func IsAVCHD(path string) bool {
+	return funk.ContainsString([]string{".MTS", ".M2TS"}, GetExt(path))
+}
+
+// GetAVCHDDate 读取AVCHD视频H.264 SEI中MDPM记录的拍摄时间,为拍摄地的当地时间,按本地时区保存,没有该信息时返回零值
+func GetAVCHDDate(filename string) (time.Time, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer file.Close()
+	data := make([]byte, avchdScanLimit)
+	n, err := io.ReadFull(file, data)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return time.Time{}, err
+	}
+	data = data[:n]
+	// MTS每个TS包188字节,M2TS在每个包前有4字节时间戳
+	packetSize, headerSize := 188, 0
+	switch {
+	case len(data) > 188 && data[0] == 0x47 && data[188] == 0x47:
+	case len(data) > 196 && data[4] == 0x47 && data[196] == 0x47:
+		packetSize, headerSize = 192, 4
+	default:
+		return time.Time{}, errNotAVCHD
+	}
+	demuxer := &avchdDemuxer{videoPID: -1, pmtPID: -1}
+	for offset := 0; offset+packetSize <= len(data); offset += packetSize {
+		packet := data[offset+headerSize : offset+packetSize]
+		if packet[0] != 0x47 {
+			return time.Time{}, errNotAVCHD
+		}
+		if date := demuxer.push(packet); !date.IsZero() {
+			return date, nil
+		}
+	}
+	return demuxer.flush(), nil
+}
+
+// avchdDemuxer 从TS包中提取H.264视频流,每收集完一个PES就查找其中的MDPM
+type avchdDemuxer struct {
+	pmtPID   int
+	videoPID int
+	pes      []byte
+}
+
+// push 处理一个TS包,找到拍摄时间时返回
+func (d *avchdDemuxer) push(packet []byte) time.Time {
+	pid := int(packet[1]&0x1f)<<8 | int(packet[2])
+	start := packet[1]&0x40 != 0
+	adaptation := packet[3] >> 4 & 0x03
+	if adaptation&0x01 == 0 {
+		return time.Time{}
+	}
+	payload := packet[4:]
+	if adaptation&0x02 != 0 {
+		if len(payload) == 0 || int(payload[0])+1 > len(payload) {
+			return time.Time{}
+		}
+		payload = payload[int(payload[0])+1:]
+	}
+	switch {
+	case pid == 0 && start:
+		d.pmtPID = parseAVCHDPAT(payload)
+	case pid == d.pmtPID && start && d.videoPID < 0:
+		d.videoPID = parseAVCHDPMT(payload)
+	case pid == d.videoPID || (d.videoPID < 0 && pid == avchdDefaultVideoPID):
+		if !start {
+			d.pes = append(d.pes, payload...)
+			return time.Time{}
+		}
+		date := d.flush()
+		// PES头: 起始码(3) + 流ID(1) + 长度(2) + 标志(2) + 头长度(1)
+		if len(payload) >= 9 && bytes.HasPrefix(payload, []byte{0, 0, 1}) && 9+int(payload[8]) <= len(payload) {
+			d.pes = append(d.pes, payload[9+int(payload[8]):]...)
+		}
+		return date
+	}
+	return time.Time{}
+}
+
+// flush 在已收集的PES中查找拍摄时间
+func (d *avchdDemuxer) flush() time.Time {
+	date := findAVCHDDate(d.pes)
+	d.pes = d.pes[:0]
+	return date
+}
+
+// parseAVCHDPAT 解析PAT,返回首个节目的PMT PID
+func parseAVCHDPAT(payload []byte) int {
+	section := avchdSection(payload)
+	// 节目列表位于8字节表头之后,末尾4字节为CRC
+	for i := 8; i+4 <= len(section)-4; i += 4 {
+		if program := int(section[i])<<8 | int(section[i+1]); program != 0 {
+			return int(section[i+2]&0x1f)<<8 | int(section[i+3])
+		}
+	}
+	return -1
+}
+
+// parseAVCHDPMT 解析PMT,返回H.264视频流的PID
+func parseAVCHDPMT(payload []byte) int {
+	section := avchdSection(payload)
+	if len(section) < 12 {
+		return -1
+	}
+	programInfoLength := int(section[10]&0x0f)<<8 | int(section[11])
+	for i := 12 + programInfoLength; i+5 <= len(section)-4; {
+		streamType := section[i]
+		pid := int(section[i+1]&0x1f)<<8 | int(section[i+2])
+		if streamType == 0x1b {
+			return pid
+		}
+		i += 5 + (int(section[i+3]&0x0f)<<8 | int(section[i+4]))
+	}
+	return -1
+}
+
+// avchdSection 去掉pointer_field,返回完整的PSI表
+func avchdSection(payload []byte) []byte {
+	if len(payload) == 0 || int(payload[0])+4 > len(payload) {
+		return nil
+	}
+	section := payload[int(payload[0])+1:]
+	length := int(section[1]&0x0f)<<8 | int(section[2])
+	if 3+length > len(section) {
+		return nil
+	}
+	return section[:3+length]
+}
+
+// findAVCHDDate 在H.264码流中查找SEI(NAL类型6)中的MDPM拍摄时间
+func findAVCHDDate(stream []byte) time.Time {
+	for {
+		start := bytes.Index(stream, []byte{0, 0, 1})
+		if start < 0 || start+3 >= len(stream) {
+			return time.Time{}
+		}
+		stream = stream[start+3:]
+		if stream[0]&0x1f != 6 {
+			continue
+		}
+		end := bytes.Index(stream, []byte{0, 0, 1})
+		if end < 0 {
+			end = len(stream)
+		}
+		if date := parseAVCHDSEI(removeEmulationPrevention(stream[1:end])); !date.IsZero() {
+			return date
+		}
+	}
+}
+
+// removeEmulationPrevention 去掉NAL中的防竞争字节(00 00 03中的03)
+func removeEmulationPrevention(nal []byte) []byte {
+	result := make([]byte, 0, len(nal))
+	zeros := 0
+	for _, b := range nal {
+		if zeros >= 2 && b == 0x03 {
+			zeros = 0
+			continue
+		}
+		if b == 0 {
+			zeros++
+		} else {
+			zeros = 0
+		}
+		result = append(result, b)
+	}
+	return result
+}
+
+// parseAVCHDSEI 解析SEI中的各条消息,查找MDPM
+func parseAVCHDSEI(sei []byte) time.Time {
+	for i := 0; i < len(sei); {
+		payloadType, payloadSize := 0, 0
+		for i < len(sei) && sei[i] == 0xff {
+			payloadType += 0xff
+			i++
+		}
+		if i >= len(sei) {
+			return time.Time{}
+		}
+		payloadType += int(sei[i])
+		i++
+		for i < len(sei) && sei[i] == 0xff {
+			payloadSize += 0xff
+			i++
+		}
+		if i >= len(sei) {
+			return time.Time{}
+		}
+		payloadSize += int(sei[i])
+		i++
+		if i+payloadSize > len(sei) {
+			return time.Time{}
+		}
+		payload := sei[i : i+payloadSize]
+		i += payloadSize
+		// user_data_unregistered: UUID(16) + "MDPM"(4) + 标签数量(1) + 标签(每个5字节)
+		if payloadType == 5 && len(payload) > 21 && bytes.Equal(payload[:16], avchdMDPMUUID) && string(payload[16:20]) == "MDPM" {
+			return parseAVCHDMDPM(payload[20:])
+		}
+	}
+	return time.Time{}
+}
+
+// parseAVCHDMDPM 解析MDPM标签中BCD编码的拍摄时间
+func parseAVCHDMDPM(data []byte) time.Time {
+	var date, clock []byte
+	count := int(data[0])
+	for i := 1; i+5 <= len(data) && count > 0; i, count = i+5, count-1 {
+		switch data[i] {
+		case avchdTagDate:
+			date = data[i+1 : i+5]
+		case avchdTagTime:
+			clock = data[i+1 : i+5]
+		}
+	}
+	if date == nil || clock == nil {
+		return time.Time{}
+	}
+	values := make([]int, 0, 7)
+	for _, b := range append(append([]byte{}, date[1:]...), clock...) {
+		if b>>4 > 9 || b&0x0f > 9 {
+			return time.Time{}
+		}
+		values = append(values, int(b>>4)*10+int(b&0x0f))
+	}
+	year, month, day, hour, minute, second := values[0]*100+values[1], values[2], values[3], values[4], values[5], values[6]
+	t := time.Date(year, time.Month(month), day, hour, minute, second, 0, time.Local)
+	// 日期超出范围时time.Date会自动进位,此时视为无效
+	if t.Month() != time.Month(month) || t.Day() != day || hour > 23 || minute > 59 || second > 59 {
+		return time.Time{}
+	}
+	return t
+}
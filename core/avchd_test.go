@@ -0,0 +1,121 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// avchdTestSEI 构造带有MDPM拍摄时间的SEI NAL,date为BCD编码的年(2字节)、月、日、时、分、秒,已加入防竞争字节
+func avchdTestSEI(date []byte) []byte {
+	payload := append(append([]byte{}, avchdMDPMUUID...), "MDPM"...)
+	payload = append(payload, 3, avchdTagDate, 0x00, date[0], date[1], date[2], avchdTagTime, date[3], date[4], date[5], date[6], 0x70, 0, 0, 0, 0)
+	rbsp := append(append([]byte{5, byte(len(payload))}, payload...), 0x80)
+	nal := []byte{0, 0, 1, 6}
+	zeros := 0
+	for _, b := range rbsp {
+		if zeros >= 2 && b <= 3 {
+			nal = append(nal, 3)
+			zeros = 0
+		}
+		nal = append(nal, b)
+		if b == 0 {
+			zeros++
+		} else {
+			zeros = 0
+		}
+	}
+	return nal
+}
+
+// avchdTestPackets 将数据拆分为TS包,最后一个包用adaptation field填充
+func avchdTestPackets(pid int, data []byte) []byte {
+	var packets []byte
+	for first := true; first || len(data) > 0; first = false {
+		n := min(len(data), 184)
+		header := []byte{0x47, byte(pid >> 8), byte(pid), 0x10}
+		if first {
+			header[1] |= 0x40
+		}
+		chunk := data[:n]
+		data = data[n:]
+		if n < 184 {
+			header[3] = 0x30
+			stuffing := make([]byte, 184-n)
+			stuffing[0] = byte(len(stuffing) - 1)
+			for i := 2; i < len(stuffing); i++ {
+				stuffing[i] = 0xff
+			}
+			chunk = append(stuffing, chunk...)
+		}
+		packets = append(append(packets, header...), chunk...)
+	}
+	return packets
+}
+
+// buildAVCHD 构造带有PAT、PMT及H.264视频流的MTS文件,sei为空时视频流中没有MDPM
+func buildAVCHD(sei []byte) []byte {
+	pat := []byte{0, 0x00, 0xb0, 13, 0, 1, 0xc1, 0, 0, 0, 1, 0xe1, 0x00, 0, 0, 0, 0}
+	// PMT中0x1100为音频流,0x1011为H.264视频流
+	pmt := []byte{0, 0x02, 0xb0, 27, 0, 1, 0xc1, 0, 0, 0xe1, 0x00, 0xf0, 0x00,
+		0x81, 0xf1, 0x00, 0xf0, 0x00, 0x1b, 0xf0, 0x11, 0xf0, 0x00, 0, 0, 0, 0}
+	es := append([]byte{0, 0, 0, 1, 0x09, 0xf0}, sei...)
+	es = append(append(es, 0, 0, 1, 0x65), make([]byte, 600)...)
+	pes := append([]byte{0, 0, 1, 0xe0, 0, 0, 0x80, 0x80, 0x05, 0x21, 0, 1, 0, 1}, es...)
+	data := avchdTestPackets(0, pat)
+	data = append(data, avchdTestPackets(0x100, pmt)...)
+	data = append(data, avchdTestPackets(0x1011, []byte{0, 0, 1, 0xe0, 0, 0, 0x80, 0x00, 0x00, 0x09, 0xf0})...)
+	return append(data, avchdTestPackets(0x1011, pes)...)
+}
+
+// toM2TS 在每个TS包前加入4字节时间戳
+func toM2TS(data []byte) []byte {
+	var result []byte
+	for i := 0; i+188 <= len(data); i += 188 {
+		result = append(append(result, 0, 0, 0, 0), data[i:i+188]...)
+	}
+	return result
+}
+
+func TestGetAVCHDDate(t *testing.T) {
+	date := []byte{0x20, 0x19, 0x07, 0x04, 0x18, 0x30, 0x05}
+	valid := buildAVCHD(avchdTestSEI(date))
+	// 截断在MDPM所在PES的第一个包中间
+	truncated := valid[:len(valid)-188*3-100]
+	// adaptation field长度超出包长度
+	corrupt := append(append([]byte{}, valid...), avchdTestPackets(0x1011, []byte{0, 0, 1, 0xe0})...)
+	corrupt[len(corrupt)-184] = 200
+	tests := []struct {
+		name    string
+		file    string
+		data    []byte
+		want    time.Time
+		wantErr bool
+	}{
+		{"MTS中的MDPM", "00001.MTS", valid, time.Date(2019, 7, 4, 18, 30, 5, 0, time.Local), false},
+		{"M2TS中的MDPM", "00001.M2TS", toM2TS(valid), time.Date(2019, 7, 4, 18, 30, 5, 0, time.Local), false},
+		{"没有MDPM", "00001.MTS", buildAVCHD(nil), time.Time{}, false},
+		{"BCD编码无效", "00001.MTS", buildAVCHD(avchdTestSEI([]byte{0x20, 0x19, 0x1a, 0x04, 0x18, 0x30, 0x05})), time.Time{}, false},
+		{"日期超出范围", "00001.MTS", buildAVCHD(avchdTestSEI([]byte{0x20, 0x19, 0x02, 0x30, 0x18, 0x30, 0x05})), time.Time{}, false},
+		{"最后一个包不完整", "00001.MTS", append(append([]byte{}, valid...), valid[:100]...), time.Date(2019, 7, 4, 18, 30, 5, 0, time.Local), false},
+		{"MDPM所在的包被截断", "00001.MTS", truncated, time.Time{}, false},
+		{"adaptation field长度错误", "00001.MTS", corrupt, time.Date(2019, 7, 4, 18, 30, 5, 0, time.Local), false},
+		{"不是TS文件", "00001.MTS", make([]byte, 1024), time.Time{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			if err := os.WriteFile(path, tt.data, 0644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := GetAVCHDDate(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetAVCHDDate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("GetAVCHDDate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package core
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+// Checkpoint 运行检查点,将已处理的文件写入journal,中断后可据此继续运行
+type Checkpoint struct {
+	journal   *Journal
+	processed map[string]bool // 已处理的文件,包含原路径与移动后的新路径
+}
+
+func NewCheckpoint(journal *Journal) *Checkpoint {
+	return &Checkpoint{journal: journal, processed: make(map[string]bool)}
+}
+
+// LoadCheckpoint 根据journal中指定运行ID的记录恢复检查点,同时返回该次运行的选项
+func LoadCheckpoint(journalPath string, journal *Journal) (*Checkpoint, *Options, error) {
+	entries, err := ReadJournal(journalPath, journal.RunID)
+	if err != nil {
+		return nil, nil, err
+	}
+	checkpoint := NewCheckpoint(journal)
+	var opts *Options
+	for _, entry := range entries {
+		switch {
+		case entry.Op == JournalOpStart:
+			opts = entry.Options
+		case entry.Op == JournalOpDone:
+			checkpoint.processed[entry.OldPath] = true
+		case entry.IsFileOp():
+			checkpoint.processed[entry.OldPath] = true
+			checkpoint.processed[entry.NewPath] = true
+		}
+	}
+	if opts == nil {
+		return nil, nil, fmt.Errorf("日志中不存在运行ID为%s的运行记录", journal.RunID)
+	}
+	return checkpoint, opts, nil
+}
+
+// IsProcessed 文件是否已在之前的运行中处理过
+func (c *Checkpoint) IsProcessed(path string) bool {
+	if c == nil || len(c.processed) == 0 {
+		return false
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	return c.processed[absPath]
+}
+
+// Done 记录文件已处理完成及拍摄时间的来源,便于核查
+func (c *Checkpoint) Done(path, source string) error {
+	if c == nil {
+		return nil
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	return c.journal.write(&JournalEntry{Op: JournalOpDone, OldPath: absPath, Source: source})
+}
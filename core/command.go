@@ -0,0 +1,139 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/thoas/go-funk"
+)
+
+// RenameTypeCommandMap 子命令与重命名类型映射
+var RenameTypeCommandMap = map[string]string{
+	"image":    RenameTypeImage,
+	"video":    RenameTypeVideo,
+	"all":      RenameTypeImageAndVideo,
+	"organize": RenameTypeOrganize,
+	"import":   RenameTypeImport,
+	"dedupe":   RenameTypeFileByHash,
+}
+
+// newRenameCommands 创建各重命名类型对应的子命令
+func newRenameCommands(opts *Options) []*cobra.Command {
+	names := funk.Keys(RenameTypeCommandMap).([]string)
+	sort.Strings(names)
+	cmds := make([]*cobra.Command, 0, len(names))
+	for _, name := range names {
+		cmds = append(cmds, newRenameCommand(opts, name, RenameTypeCommandMap[name]))
+	}
+	return cmds
+}
+
+// newRenameCommand 创建单个重命名子命令
+func newRenameCommand(opts *Options, name, renameType string) *cobra.Command {
+	var matchFailureHandler string
+	cmd := &cobra.Command{
+		Use:   name + " <dir>",
+		Short: RenameTypeTextMap[renameType],
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts.RenameType = renameType
+			opts.Dir = args[0]
+			if err := CheckDir(opts.Dir); err != nil {
+				return err
+			}
+			if renameType == RenameTypeImport {
+				if err := CheckImportTarget(opts.Dir, opts.Target); err != nil {
+					return err
+				}
+			}
+			if renameType != RenameTypeFileByHash {
+				handlerType, err := ParseMatchFailureHandlerType(matchFailureHandler)
+				if err != nil {
+					return err
+				}
+				opts.MatchFailureHandlerType = handlerType
+			}
+			Process(opts)
+			return nil
+		},
+	}
+	cmd.Flags().BoolVarP(&opts.Yes, "yes", "y", false, "跳过操作确认,直接执行")
+	cmd.Flags().BoolVar(&opts.DryRun, "dry-run", false, "仅打印完整的重命名计划,不修改任何文件")
+	if renameType != RenameTypeFileByHash {
+		cmd.Flags().StringVar(&matchFailureHandler, "on-failure", "ignore", matchFailureHandlerUsage())
+	}
+	switch renameType {
+	case RenameTypeOrganize:
+		cmd.Flags().StringVar(&opts.Target, "target", "", "整理后的目标目录,默认为处理的目录")
+	case RenameTypeImport:
+		cmd.Use = name + " <source-dir>"
+		cmd.Flags().StringVar(&opts.Target, "target", "", "图库目录(必填)")
+		cmd.Flags().BoolVar(&opts.DeleteSource, "delete-source", false, "复制并校验md5通过后删除原文件,默认不修改原文件")
+	}
+	if renameType == RenameTypeOrganize || renameType == RenameTypeImport {
+		cmd.Flags().StringVar(&opts.FolderTemplate, "folder", DefaultFolderTemplate, "文件夹模板,以/分隔多级文件夹,可用变量与文件名模板相同")
+	}
+	return cmd
+}
+
+// newUndoCommand 创建撤销命令
+func newUndoCommand(opts *Options) *cobra.Command {
+	var force bool
+	cmd := &cobra.Command{
+		Use:   "undo <run-id>",
+		Short: "根据重命名日志撤销指定运行ID的全部操作",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return RunUndo(opts, args[0], force)
+		},
+	}
+	cmd.Flags().BoolVarP(&opts.Yes, "yes", "y", false, "跳过操作确认,直接执行")
+	cmd.Flags().BoolVar(&force, "force", false, "文件内容已被修改时仍然恢复")
+	return cmd
+}
+
+// newResumeCommand 创建继续运行命令
+func newResumeCommand(opts *Options) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "resume [run-id]",
+		Short: "继续执行中断的运行,不指定运行ID时继续最近一次中断的运行",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var runID string
+			if len(args) > 0 {
+				runID = args[0]
+			}
+			return Resume(opts, runID)
+		},
+	}
+	cmd.Flags().BoolVarP(&opts.Yes, "yes", "y", false, "跳过操作确认,直接执行")
+	return cmd
+}
+
+// ParseMatchFailureHandlerType 解析日期获取失败的处理方式,支持名称或编号
+func ParseMatchFailureHandlerType(value string) (int, error) {
+	if handlerType, ok := MatchFailureHandlerTypeNameMap[value]; ok {
+		return handlerType, nil
+	}
+	if number, err := strconv.Atoi(value); err == nil && MatchFailureHandlerTypeMap[number] != 0 {
+		return MatchFailureHandlerTypeMap[number], nil
+	}
+	return 0, fmt.Errorf("不支持的日期获取失败处理方式:%s", value)
+}
+
+// matchFailureHandlerUsage 日期获取失败处理方式参数的说明
+func matchFailureHandlerUsage() string {
+	names := funk.Keys(MatchFailureHandlerTypeNameMap).([]string)
+	sort.Slice(names, func(i, j int) bool {
+		return MatchFailureHandlerTypeNameMap[names[i]] < MatchFailureHandlerTypeNameMap[names[j]]
+	})
+	lines := make([]string, 0, len(names))
+	for _, name := range names {
+		handlerType := MatchFailureHandlerTypeNameMap[name]
+		lines = append(lines, fmt.Sprintf("%s(%d): %s", name, handlerType, MatchFailureHandlerTypeTextMap[handlerType]))
+	}
+	return "没有拍摄日期的文件的处理方式\n" + strings.Join(lines, "\n")
+}
@@ -0,0 +1,94 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+// ConfigFileName 配置文件名
+const ConfigFileName = "config.yaml"
+
+// Config 配置文件内容,命令行参数优先于配置文件
+type Config struct {
+	Template         string            `yaml:"template"`          // 文件名模板
+	FolderTemplate   string            `yaml:"folder_template"`   // 整理模式的文件夹模板
+	PrefixRules      []*PrefixRule     `yaml:"prefix_rules"`      // 自定义前缀规则
+	ExtCase          string            `yaml:"ext_case"`          // 扩展名大小写处理方式
+	ExtAliases       map[string]string `yaml:"ext_aliases"`       // 扩展名别名
+	FilenameRules    []*FilenameRule   `yaml:"filename_rules"`    // 自定义文件名规则
+	DateSources      []string          `yaml:"date_sources"`      // 拍摄时间来源
+	TimeZone         string            `yaml:"time_zone"`         // 时区处理方式
+	MetadataBackends []string          `yaml:"metadata_backends"` // 元数据读取方式
+	TakeoutJSON      string            `yaml:"takeout_json"`      // Takeout JSON附属文件的处理方式
+}
+
+// DefaultConfigPath 默认的配置文件路径
+func DefaultConfigPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, AppDir, ConfigFileName), nil
+}
+
+// LoadConfig 读取配置文件,未指定路径且默认配置文件不存在时返回空配置
+func LoadConfig(path string) (*Config, error) {
+	config := &Config{}
+	if path == "" {
+		defaultPath, err := DefaultConfigPath()
+		if err != nil {
+			return config, nil
+		}
+		if _, err = os.Stat(defaultPath); os.IsNotExist(err) {
+			return config, nil
+		}
+		path = defaultPath
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if err = yaml.UnmarshalStrict(data, config); err != nil {
+		return nil, fmt.Errorf("配置文件%s格式错误:%v", path, err)
+	}
+	return config, nil
+}
+
+// Apply 将配置应用到运行选项,已通过命令行指定的参数不会被覆盖
+func (c *Config) Apply(cmd *cobra.Command, opts *Options) {
+	if c.Template != "" && !cmd.Flags().Changed("template") {
+		opts.Template = c.Template
+	}
+	// 文件夹模板仅整理命令支持,未注册该参数时Changed返回false
+	if c.FolderTemplate != "" && !cmd.Flags().Changed("folder") {
+		opts.FolderTemplate = c.FolderTemplate
+	}
+	if len(c.PrefixRules) > 0 && !cmd.Flags().Changed("prefix-rule") {
+		opts.PrefixRules = c.PrefixRules
+	}
+	if c.ExtCase != "" && !cmd.Flags().Changed("ext-case") {
+		opts.ExtCase = c.ExtCase
+	}
+	if len(c.ExtAliases) > 0 && !cmd.Flags().Changed("ext-alias") {
+		opts.ExtAliases = c.ExtAliases
+	}
+	if len(c.FilenameRules) > 0 && !cmd.Flags().Changed("filename-rule") {
+		opts.FilenameRules = c.FilenameRules
+	}
+	if len(c.DateSources) > 0 && !cmd.Flags().Changed("date-source") {
+		opts.DateSources = c.DateSources
+	}
+	if c.TimeZone != "" && !cmd.Flags().Changed("timezone") {
+		opts.TimeZone = c.TimeZone
+	}
+	if len(c.MetadataBackends) > 0 && !cmd.Flags().Changed("metadata-backend") {
+		opts.MetadataBackends = c.MetadataBackends
+	}
+	if c.TakeoutJSON != "" && !cmd.Flags().Changed("takeout-json") {
+		opts.TakeoutJSON = c.TakeoutJSON
+	}
+}
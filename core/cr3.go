@@ -0,0 +1,111 @@
+package core
+
+import (
+	"bytes"
+	"errors"
+	"os"
+
+	"github.com/thoas/go-funk"
+)
+
+// cr3CanonUUID CR3中保存元数据的uuid box
+var cr3CanonUUID = []byte{0x85, 0xc0, 0xb6, 0x87, 0x82, 0x0f, 0x11, 0xe0, 0x81, 0x11, 0xf4, 0xce, 0x46, 0x2b, 0x6a, 0x48}
+
+// cr3XMPUUID CR3中保存XMP的uuid box
+var cr3XMPUUID = []byte{0xbe, 0x7a, 0xcf, 0xcb, 0x97, 0xa9, 0x42, 0xe8, 0x9c, 0x71, 0x99, 0x94, 0x91, 0xe3, 0xaf, 0xac}
+
+// cr3MetadataBoxes CR3中以TIFF结构保存元数据的box及其中读取的标签
+var cr3MetadataBoxes = map[string]map[uint16]string{
+	"CMT1": tiffIFD0Tags, // IFD0
+	"CMT2": tiffExifTags, // Exif IFD
+	"CMT4": tiffGPSTags,  // GPS IFD
+}
+
+// errNotCR3 文件不是CR3格式
+var errNotCR3 = errors.New("不是有效的CR3文件")
+
+// IsCR3 判断文件是否为佳能CR3格式的RAW图片
+func IsCR3(path string) bool {
+	return funk.ContainsString([]string{".CR3"}, GetExt(path))
+}
+
+// GetCR3ExifTags 读取CR3文件moov中佳能uuid box里CMT1/CMT2/CMT4保存的EXIF标签
+func GetCR3ExifTags(filename string) (map[string]interface{}, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	boxes, err := readISOBMFFBoxes(file, 0, info.Size())
+	if err != nil || len(boxes) == 0 || boxes[0].boxType != "ftyp" {
+		return nil, errNotCR3
+	}
+	tags := make(map[string]interface{})
+	for _, box := range boxes {
+		if box.boxType != "moov" {
+			continue
+		}
+		children, err := readISOBMFFBoxes(file, box.offset, box.size)
+		if err != nil {
+			return nil, errNotCR3
+		}
+		for _, child := range children {
+			if child.boxType != "uuid" || child.size < 16 {
+				continue
+			}
+			uuid := make([]byte, 16)
+			if _, err = file.ReadAt(uuid, child.offset); err != nil || !bytes.Equal(uuid, cr3CanonUUID) {
+				continue
+			}
+			entries, err := readISOBMFFBoxes(file, child.offset+16, child.size-16)
+			if err != nil {
+				return nil, errNotCR3
+			}
+			for _, entry := range entries {
+				if names := cr3MetadataBoxes[entry.boxType]; names != nil {
+					if err = readTIFFTags(file, entry.offset, names, tags); err != nil {
+						return nil, err
+					}
+				}
+			}
+			return tags, nil
+		}
+	}
+	return tags, nil
+}
+
+// GetCR3XMP 读取CR3文件顶层uuid box中的XMP,没有时返回nil
+func GetCR3XMP(filename string) ([]byte, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	boxes, err := readISOBMFFBoxes(file, 0, info.Size())
+	if err != nil || len(boxes) == 0 || boxes[0].boxType != "ftyp" {
+		return nil, errNotCR3
+	}
+	// 预览图等uuid box可能有数MB,先只读取UUID
+	uuid := make([]byte, 16)
+	for _, box := range boxes {
+		if box.boxType != "uuid" || box.size < 16 {
+			continue
+		}
+		if _, err = file.ReadAt(uuid, box.offset); err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(uuid, cr3XMPUUID) {
+			continue
+		}
+		return readISOBMFFBox(file, &isobmffBox{boxType: box.boxType, offset: box.offset + 16, size: box.size - 16})
+	}
+	return nil, nil
+}
@@ -0,0 +1,57 @@
+package core
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// isobmffTestBox 构造ISOBMFF box
+func isobmffTestBox(boxType string, payload ...[]byte) []byte {
+	var data []byte
+	for _, p := range payload {
+		data = append(data, p...)
+	}
+	header := make([]byte, 8)
+	binary.BigEndian.PutUint32(header, uint32(len(data)+8))
+	copy(header[4:], boxType)
+	return append(header, data...)
+}
+
+func TestCR3CaptureTime(t *testing.T) {
+	le := binary.ByteOrder(binary.LittleEndian)
+	ftyp := isobmffTestBox("ftyp", []byte("crx \x00\x00\x00\x01crx isom"))
+	canon := isobmffTestBox("uuid", cr3CanonUUID,
+		isobmffTestBox("CNCV", []byte("CanonCR3_001/00.09.00/00.00.00")),
+		isobmffTestBox("CMT1", buildTIFF(le, 42, map[uint16]string{0x010f: "Canon", 0x0110: "Canon EOS R5"}, nil)),
+		// CMT2为Exif IFD,以TIFF头开始
+		isobmffTestBox("CMT2", buildTIFF(le, 42, testRawExif, nil)),
+	)
+	other := isobmffTestBox("uuid", make([]byte, 16), make([]byte, 64))
+	data := append(ftyp, isobmffTestBox("moov", other, canon, isobmffTestBox("trak"))...)
+	path := filepath.Join(t.TempDir(), "IMG_0001.CR3")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	assertRawCaptureTime(t, path, "Canon")
+}
+
+func TestGetCR3XMP(t *testing.T) {
+	xmp := []byte(`<x:xmpmeta xmlns:x="adobe:ns:meta/"><rdf:RDF><rdf:Description xmp:CreateDate="2023-04-05T06:07:08"/></rdf:RDF></x:xmpmeta>`)
+	ftyp := isobmffTestBox("ftyp", []byte("crx \x00\x00\x00\x01crx isom"))
+	// 预览图uuid box大于单个box的读取上限,查找XMP时不应读取其内容
+	preview := isobmffTestBox("uuid", make([]byte, 16), make([]byte, isobmffMaxBoxSize+1))
+	data := append(append(ftyp, preview...), isobmffTestBox("uuid", cr3XMPUUID, xmp)...)
+	path := filepath.Join(t.TempDir(), "IMG_0001.CR3")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	packet, err := GetCR3XMP(path)
+	if err != nil {
+		t.Fatalf("GetCR3XMP() error = %v", err)
+	}
+	if string(packet) != string(xmp) {
+		t.Errorf("GetCR3XMP() = %q, want %q", packet, xmp)
+	}
+}
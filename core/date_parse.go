@@ -0,0 +1,66 @@
+package core
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// dateTimePattern 元数据中常见的时间格式,日期分隔符可以是:-/.,时间及秒可省略,末尾可带时区
+// 如2006:01:02 15:04:05、2006:01:02 15:04、2006-01-02T15:04:05.000Z、2006-01-02 15:04:05 UTC、2006-01-02T15:04:05+0800
+var dateTimePattern = regexp.MustCompile(`^(\d{4})[:\-/.](\d{1,2})[:\-/.](\d{1,2})(?:[T ]+(\d{1,2}):(\d{1,2})(?::(\d{1,2})(?:[.,](\d+))?)?)?\s*(Z|UTC|GMT|[+-]\d{2}(?::?\d{2})?)?$`)
+
+// ParseDateTime 宽松地解析元数据中的时间,没有时区时按location保存
+// 值为空或为0000:00:00 00:00:00等占位值时返回nil,无法解析时返回错误,调用方应视为没有该时间
+func ParseDateTime(value string, location *time.Location) (*MetadataDate, error) {
+	value = strings.Trim(value, " \t\r\n\x00")
+	// mediainfo旧版本的格式为UTC 2006-01-02 15:04:05
+	if rest, ok := strings.CutPrefix(value, "UTC "); ok {
+		value = rest + " UTC"
+	}
+	// 相机未设置时间时写入全0或空格占位
+	if strings.Trim(value, "0:-/. TZUC") == "" {
+		return nil, nil
+	}
+	matches := dateTimePattern.FindStringSubmatch(value)
+	if matches == nil {
+		return nil, fmt.Errorf("无法解析时间%s", value)
+	}
+	values := make([]int, 6)
+	for i, part := range matches[1:7] {
+		if part != "" {
+			values[i], _ = strconv.Atoi(part)
+		}
+	}
+	year, month, day, hour, minute, second := values[0], values[1], values[2], values[3], values[4], values[5]
+	var nanosecond int
+	if fraction := matches[7]; fraction != "" {
+		nanosecond, _ = strconv.Atoi((fraction + "00000000")[:9])
+	}
+	date := &MetadataDate{}
+	switch zone := matches[8]; {
+	case zone == "":
+	case zone == "Z" || zone == "UTC" || zone == "GMT":
+		location, date.OffsetKnown = time.UTC, true
+	default:
+		offset := strings.ReplaceAll(zone, ":", "")
+		hours, _ := strconv.Atoi(offset[1:3])
+		var minutes int
+		if len(offset) == 5 {
+			minutes, _ = strconv.Atoi(offset[3:5])
+		}
+		seconds := hours*3600 + minutes*60
+		if offset[0] == '-' {
+			seconds = -seconds
+		}
+		location, date.OffsetKnown = time.FixedZone(zone, seconds), true
+	}
+	date.Time = time.Date(year, time.Month(month), day, hour, minute, second, nanosecond, location)
+	// 日期超出范围时time.Date会自动进位,此时视为无效
+	if year == 0 || date.Time.Month() != time.Month(month) || date.Time.Day() != day || hour > 23 || minute > 59 || second > 59 {
+		return nil, fmt.Errorf("无法解析时间%s", value)
+	}
+	return date, nil
+}
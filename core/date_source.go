@@ -0,0 +1,159 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/djherbis/times"
+	"github.com/thoas/go-funk"
+)
+
+// 拍摄时间来源
+const (
+	DateSourceExifOriginal  = "exif:DateTimeOriginal"  // EXIF拍摄时间
+	DateSourceExifDigitized = "exif:DateTimeDigitized" // EXIF数字化时间
+	DateSourceExifDateTime  = "exif:DateTime"          // EXIF修改时间
+	DateSourceXMP           = "xmp"                    // 图片内嵌XMP中的拍摄时间
+	DateSourcePNGCreated    = "png:CreationTime"       // PNG文本块中的创建时间
+	DateSourcePNGModified   = "png:tIME"               // PNG最后修改时间
+	DateSourceVideoRecorded = "video:Recorded_Date"    // 视频拍摄时间
+	DateSourceVideoEncoded  = "video:Encoded_Date"     // 视频编码时间
+	DateSourceVideoTagged   = "video:Tagged_Date"      // 视频标记时间
+	DateSourceFilename      = "filename"               // 文件名中的时间
+	DateSourceSidecar       = "sidecar"                // XMP附属文件中的时间
+	DateSourceTakeout       = "takeout"                // Google Takeout JSON附属文件中的时间
+	DateSourceBirthTime     = "birthtime"              // 文件创建时间
+	DateSourceModTime       = "mtime"                  // 文件修改时间
+)
+
+// DateSourceCreationTime 没有拍摄日期时按creation-time处理方式使用的文件创建/修改时间,不可在来源链中指定
+const DateSourceCreationTime = "creation-time"
+
+// exifDateSourcePrefix EXIF时间来源的前缀
+const exifDateSourcePrefix = "exif:"
+
+// DateSourceTextMap 拍摄时间来源说明
+var DateSourceTextMap = map[string]string{
+	DateSourceExifOriginal:  "图片EXIF中的拍摄时间",
+	DateSourceExifDigitized: "图片EXIF中的数字化时间",
+	DateSourceExifDateTime:  "图片EXIF中的修改时间",
+	DateSourceXMP:           "图片内嵌XMP中的拍摄时间,Lightroom等软件修改的时间通常写入XMP",
+	DateSourcePNGCreated:    "PNG文本块中的Creation Time",
+	DateSourcePNGModified:   "PNG tIME块中的最后修改时间,截图等软件通常在保存时写入",
+	DateSourceVideoRecorded: "视频的拍摄时间,如QuickTime元数据中的拍摄时间",
+	DateSourceVideoEncoded:  "视频的编码时间,如mvhd创建时间",
+	DateSourceVideoTagged:   "视频的标记时间,如mdhd创建时间",
+	DateSourceFilename:      "按文件名规则推断的时间,如mmexport1690000000000.jpg、IMG-20230101-WA0001.jpg、IMG_20250606_121601.JPG",
+	DateSourceSidecar:       "同名XMP附属文件(photo.xmp或photo.jpg.xmp)中的拍摄时间",
+	DateSourceTakeout:       "Google Takeout导出的JSON附属文件(photo.jpg.json等)中的photoTakenTime",
+	DateSourceBirthTime:     "文件创建时间,部分文件系统不支持",
+	DateSourceModTime:       "文件修改时间",
+}
+
+// DefaultDateSources 默认的拍摄时间来源,在Lightroom、darktable等软件中修正的时间写入XMP,优先于相机记录的EXIF
+var DefaultDateSources = []string{
+	DateSourceSidecar, DateSourceXMP, DateSourceExifOriginal, DateSourcePNGCreated, DateSourcePNGModified,
+	DateSourceVideoRecorded, DateSourceVideoEncoded, DateSourceVideoTagged, DateSourceTakeout,
+}
+
+// ValidateDateSources 校验拍摄时间来源
+func ValidateDateSources(sources []string) error {
+	if len(sources) == 0 {
+		return fmt.Errorf("至少需要指定一个拍摄时间来源")
+	}
+	for _, source := range sources {
+		if DateSourceTextMap[source] == "" {
+			return fmt.Errorf("拍摄时间来源%s不存在,可用来源:%s", source, strings.Join(DateSources(), ","))
+		}
+	}
+	return nil
+}
+
+// DateSources 返回排序后的全部拍摄时间来源
+func DateSources() []string {
+	sources := funk.Keys(DateSourceTextMap).([]string)
+	sort.Strings(sources)
+	return sources
+}
+
+// DateChain 拍摄时间来源链,按顺序使用首个能获取到的时间
+type DateChain struct {
+	Sources       []string
+	FilenameRules []*FilenameRule // 从文件名推断时间的规则,自定义规则在前
+	Takeout       *TakeoutIndex   // Takeout JSON附属文件的查找缓存,重命名时与Renamer共用
+}
+
+func NewDateChain(sources []string, filenameRules []*FilenameRule) *DateChain {
+	return &DateChain{Sources: sources, FilenameRules: filenameRules, Takeout: NewTakeoutIndex()}
+}
+
+// NeedsMetadata 来源链中是否包含需要读取元数据的来源
+func (c *DateChain) NeedsMetadata() bool {
+	for _, source := range c.Sources {
+		if metadataDateSources[source] {
+			return true
+		}
+	}
+	return false
+}
+
+// metadataDateSources 从元数据中读取的来源
+var metadataDateSources = map[string]bool{
+	DateSourceExifOriginal:  true,
+	DateSourceExifDigitized: true,
+	DateSourceExifDateTime:  true,
+	DateSourceXMP:           true,
+	DateSourcePNGCreated:    true,
+	DateSourcePNGModified:   true,
+	DateSourceVideoRecorded: true,
+	DateSourceVideoEncoded:  true,
+	DateSourceVideoTagged:   true,
+}
+
+// Resolve 按来源链获取拍摄时间,返回时间及来源,元数据中的来源附带读取方式,如video:Recorded_Date(native),文件名来源附带规则名称,如filename(wechat)
+// 所有来源均没有时间时返回nil
+func (c *DateChain) Resolve(path string, metadata *Metadata) (*MetadataDate, string) {
+	for _, source := range c.Sources {
+		if metadataDateSources[source] {
+			if date := metadata.Dates[source]; date != nil {
+				return date, fmt.Sprintf("%s(%s)", source, date.Provider)
+			}
+			continue
+		}
+		if source == DateSourceFilename {
+			if date, rule := MatchFilenameDate(c.FilenameRules, path); date != nil {
+				return date, fmt.Sprintf("%s(%s)", source, rule)
+			}
+			continue
+		}
+		if date := c.resolveFileDate(source, path); date != nil {
+			return date, source
+		}
+	}
+	return nil, ""
+}
+
+// resolveFileDate 从附属文件或文件时间中获取时间
+func (c *DateChain) resolveFileDate(source, path string) *MetadataDate {
+	switch source {
+	case DateSourceSidecar:
+		return ParseXMPDate(ReadSidecarXMP(path))
+	case DateSourceTakeout:
+		return c.Takeout.ReadDate(path)
+	case DateSourceBirthTime:
+		fileTimes, err := times.Stat(path)
+		if err != nil || !fileTimes.HasBirthTime() {
+			return nil
+		}
+		return &MetadataDate{Time: fileTimes.BirthTime(), OffsetKnown: true}
+	case DateSourceModTime:
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil
+		}
+		return &MetadataDate{Time: info.ModTime(), OffsetKnown: true}
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/thoas/go-funk"
+)
+
+// 扩展名大小写处理方式
+const (
+	ExtCasePreserve = "preserve" // 保留原扩展名
+	ExtCaseLower    = "lower"    // 统一为小写
+	ExtCaseUpper    = "upper"    // 统一为大写
+)
+
+// DefaultExtCase 默认的扩展名大小写处理方式,与之前版本一致
+const DefaultExtCase = ExtCaseUpper
+
+// ExtCaseTextMap 扩展名大小写处理方式说明
+var ExtCaseTextMap = map[string]string{
+	ExtCasePreserve: "保留原扩展名,如photo.jpg -> IMG_20250606_121601.jpg",
+	ExtCaseLower:    "统一为小写,如photo.JPG -> IMG_20250606_121601.jpg",
+	ExtCaseUpper:    "统一为大写,如photo.jpg -> IMG_20250606_121601.JPG",
+}
+
+// ExtPolicy 扩展名规范化策略,先按别名替换,再处理大小写
+type ExtPolicy struct {
+	Case    string
+	aliases map[string]string // 小写的原扩展名与替换后扩展名映射
+}
+
+func NewExtPolicy(extCase string, aliases map[string]string) *ExtPolicy {
+	p := &ExtPolicy{Case: extCase, aliases: make(map[string]string)}
+	for from, to := range aliases {
+		p.aliases[strings.ToLower(normalizeExt(from))] = normalizeExt(to)
+	}
+	return p
+}
+
+// ParseExtAlias 解析命令行中的扩展名别名,格式为 原扩展名1,原扩展名2=新扩展名
+func ParseExtAlias(spec string) (map[string]string, error) {
+	from, to, ok := strings.Cut(spec, "=")
+	if !ok || strings.TrimSpace(from) == "" || strings.TrimSpace(to) == "" {
+		return nil, fmt.Errorf("扩展名别名%s格式错误,正确格式如.jpeg,.jfif=.jpg", spec)
+	}
+	aliases := make(map[string]string)
+	for _, ext := range strings.Split(from, ",") {
+		aliases[strings.TrimSpace(ext)] = strings.TrimSpace(to)
+	}
+	return aliases, nil
+}
+
+// ValidateExtPolicy 校验扩展名大小写处理方式及别名
+func ValidateExtPolicy(extCase string, aliases map[string]string) error {
+	if ExtCaseTextMap[extCase] == "" {
+		return fmt.Errorf("扩展名大小写处理方式%s不存在,可用方式:%s", extCase, strings.Join(ExtCases(), ","))
+	}
+	for from, to := range aliases {
+		for _, ext := range []string{from, to} {
+			name := strings.TrimPrefix(ext, ".")
+			if name == "" || strings.ContainsAny(name, templateIllegalChars+".") {
+				return fmt.Errorf("扩展名别名%s=%s格式错误,扩展名不能为空且不能包含以下字符:%s.", from, to, templateIllegalChars)
+			}
+		}
+	}
+	return nil
+}
+
+// ExtCases 返回排序后的全部扩展名大小写处理方式
+func ExtCases() []string {
+	cases := funk.Keys(ExtCaseTextMap).([]string)
+	sort.Strings(cases)
+	return cases
+}
+
+// Normalize 规范化扩展名(含.),没有扩展名时返回空
+func (p *ExtPolicy) Normalize(ext string) string {
+	if ext == "" {
+		return ""
+	}
+	if alias, ok := p.aliases[strings.ToLower(ext)]; ok {
+		ext = alias
+	}
+	switch p.Case {
+	case ExtCaseLower:
+		return strings.ToLower(ext)
+	case ExtCasePreserve:
+		return ext
+	}
+	return strings.ToUpper(ext)
+}
+
+// normalizeExt 补全扩展名开头的.
+func normalizeExt(ext string) string {
+	return "." + strings.TrimPrefix(strings.TrimSpace(ext), ".")
+}
@@ -1,19 +1,15 @@
 package core
 
 import (
-	"bytes"
+	"context"
 	"crypto/md5"
 	"encoding/hex"
 	"errors"
 	"fmt"
 	"github.com/djherbis/times"
-	"github.com/dsoprea/go-exif/v3"
 	"github.com/thoas/go-funk"
-	"github.com/tidwall/gjson"
-	"hyue418/go-rename/common"
 	"io"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
 	"time"
@@ -24,7 +20,7 @@ func IsImage(path string) bool {
 	extensions := []string{
 		".RW2", ".PNG", ".HEIC", ".CUR", ".CRW", ".JPEG", ".HEIF", ".AVIF", ".ICO", ".ORF", ".PSD", ".BMP", ".SVG",
 		".JPG", ".PCX", ".DNG", ".TIFF", ".GIF", ".TIF", ".ARW", ".SR2", ".RAF", ".LIVP", ".NEF", ".CR2", ".JFIF",
-		".RAW", ".WEBP",
+		".RAW", ".WEBP", ".CR3", ".NRW", ".PEF", ".SRW", ".3FR", ".IIQ", ".X3F",
 	}
 	return funk.ContainsString(extensions, GetExt(path))
 }
@@ -41,35 +37,35 @@ func IsVideo(path string) bool {
 	return funk.ContainsString(extensions, GetExt(path))
 }
 
-// GetExt 获取文件扩展名
+// IsRaw 判断文件是否为RAW格式图片
+func IsRaw(path string) bool {
+	extensions := []string{
+		".RW2", ".CRW", ".ORF", ".DNG", ".ARW", ".SR2", ".RAF", ".NEF", ".CR2", ".RAW", ".CR3", ".NRW", ".PEF", ".SRW",
+		".3FR", ".IIQ", ".X3F",
+	}
+	return funk.ContainsString(extensions, GetExt(path))
+}
+
+// GetExt 获取大写的文件扩展名,用于判断文件类型,生成文件名时使用ExtPolicy
 func GetExt(path string) string {
 	return strings.ToUpper(filepath.Ext(path))
 }
 
 // GetNameByFileHash 以文件md5作为文件名
-func GetNameByFileHash(filePath string, info os.FileInfo) (string, error) {
-	hash, err := GetFileHash(filePath)
+func GetNameByFileHash(ctx context.Context, filePath string, info os.FileInfo, extPolicy *ExtPolicy) (string, error) {
+	hash, err := GetFileHash(ctx, filePath)
 	if err != nil {
 		return "", err
 	}
-	return filepath.Join(filepath.Dir(filePath), hash+GetExt(info.Name())), nil
+	return filepath.Join(filepath.Dir(filePath), hash+extPolicy.Normalize(filepath.Ext(info.Name()))), nil
 }
 
-// GetOriginalTime 获取图片原始拍摄时间
-func GetOriginalTime(filePath string) (string, error) {
-	dateTimeOriginal, _, _, err := GetExifTime(filePath)
-	if err != nil {
-		return "", err
-	}
-	return dateTimeOriginal, nil
-}
-
-// GetFileCreationTime 获取文件创建时间
-func GetFileCreationTime(filePath string) (string, error) {
+// GetFileCreationTime 获取文件创建时间,创建时间晚于修改时间时返回修改时间
+func GetFileCreationTime(filePath string) (time.Time, error) {
 	// 获取文件时间信息
 	fileTimes, err := times.Stat(filePath)
 	if err != nil {
-		return "", err
+		return time.Time{}, err
 	}
 	// 创建时间
 	birthTime := fileTimes.BirthTime()
@@ -77,93 +73,9 @@ func GetFileCreationTime(filePath string) (string, error) {
 	modTime := fileTimes.ModTime()
 	// 对比返回最小时间
 	if birthTime.Before(modTime) {
-		return birthTime.Format("2006-01-02 15:04:05"), nil
+		return birthTime, nil
 	}
-	return modTime.Format("2006-01-02 15:04:05"), nil
-}
-
-// GetExifTime 获取exif时间
-func GetExifTime(filePath string) (dateTimeOriginal, dateTimeDigitized, dateTime string, err error) {
-	opt := exif.ScanOptions{}
-	dt, err := exif.SearchFileAndExtractExif(filePath)
-	if errors.Is(err, exif.ErrNoExif) {
-		return "", "", "", nil
-	} else if err != nil {
-		return
-	}
-	ets, _, err := exif.GetFlatExifData(dt, &opt)
-	if err != nil {
-		return
-	}
-	for _, et := range ets {
-		switch et.TagName {
-		case "DateTimeOriginal":
-			dateTimeOriginal, err = FormatExifTime(fmt.Sprintf("%s", et.Value))
-			if err != nil {
-				return "", "", "", err
-			}
-		case "DateTimeDigitized":
-			dateTimeDigitized, err = FormatExifTime(fmt.Sprintf("%s", et.Value))
-			if err != nil {
-				return "", "", "", err
-			}
-		case "DateTime":
-			dateTime, err = FormatExifTime(fmt.Sprintf("%s", et.Value))
-			if err != nil {
-				return "", "", "", err
-			}
-		default:
-			continue
-		}
-	}
-	return dateTimeOriginal, dateTimeDigitized, dateTime, nil
-}
-
-// GetVideoDate 获取视频文件拍摄日期
-func GetVideoDate(filename string) (string, error) {
-	cmd := exec.Command("mediainfo", "--Output=JSON", filename)
-	var out bytes.Buffer
-	cmd.Stdout = &out
-	if err := cmd.Run(); err != nil {
-		return "", err
-	}
-	data := strings.TrimSpace(out.String())
-	if data == "" {
-		return "", nil
-	}
-	// 拍摄时间
-	recordedDate := gjson.Get(data, `media.track.#(@type="General").Recorded_Date`).String()
-	if recordedDate != "" {
-		// 解析时间字符串
-		res, err := time.Parse("2006-01-02T15:04:05-0700", recordedDate)
-		if err == nil {
-			return res.Format("2006-01-02 15:04:05"), nil
-		}
-		// 解析错误时使用Encoded_Date编码时间
-	}
-	// 编码时间
-	encodedDate := gjson.Get(data, `media.track.#(@type="General").Encoded_Date`).String()
-	if encodedDate != "" {
-		// 解析时间字符串
-		res, err := time.Parse("2006-01-02 15:04:05 MST", encodedDate)
-		if err != nil {
-			return "", err
-		}
-		return res.Format("2006-01-02 15:04:05"), nil
-	}
-	return "", nil
-}
-
-// FormatExifTime 格式化Exif时间
-func FormatExifTime(input string) (string, error) {
-	if input == "" {
-		return "", nil
-	}
-	res, err := time.Parse("2006:01:02 15:04:05", input)
-	if err != nil {
-		return "", err
-	}
-	return res.Format("2006-01-02 15:04:05"), nil
+	return modTime, nil
 }
 
 // IsHiddenFile 是否为隐藏文件
@@ -171,42 +83,40 @@ func IsHiddenFile(fileName string) bool {
 	return len(fileName) > 0 && fileName[0] == '.'
 }
 
-// GetFileTime 获取文件时间
-func GetFileTime(filePath string) (dateTimeOriginal, dateTimeDigitized, dateTime, modificationTime string, err error) {
-	dateTimeOriginal, dateTimeDigitized, dateTime, err = GetExifTime(filePath)
-	if err != nil {
-		return
-	}
-	fileInfo, err := os.Stat(filePath)
-	if err != nil {
-		return
-	}
-	return dateTimeOriginal, dateTimeDigitized, dateTime, fileInfo.ModTime().Format("2006-01-02 15:04:05"), nil
-}
-
 // GetFileHash 计算文件的 MD5 哈希值
-func GetFileHash(filePath string) (string, error) {
+func GetFileHash(ctx context.Context, filePath string) (string, error) {
 	file, err := os.Open(filePath)
 	if err != nil {
 		return "", err
 	}
 	defer file.Close()
 	hasher := md5.New()
-	if _, err := io.Copy(hasher, file); err != nil {
+	if _, err := io.Copy(hasher, &contextReader{ctx: ctx, reader: file}); err != nil {
 		return "", err
 	}
 	return hex.EncodeToString(hasher.Sum(nil)), nil
 }
 
-// GetDateFileName 获取带日期的文件名(含后缀名)
-func GetDateFileName(date, fileName string) string {
-	prefix := "FIL"
-	if IsImage(fileName) {
-		prefix = "IMG"
-	} else if IsVideo(fileName) {
-		prefix = "VID"
+// contextReader 上下文取消后停止读取
+type contextReader struct {
+	ctx    context.Context
+	reader io.Reader
+}
+
+func (r *contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
 	}
-	return fmt.Sprintf("%s_%s%s", prefix, common.FormatDate(date), GetExt(fileName))
+	return r.reader.Read(p)
+}
+
+// GetExifCamera 获取exif中的相机厂商及型号
+func GetExifCamera(filePath string) (cameraMake, cameraModel string, err error) {
+	tags, err := readExifTags(filePath)
+	if err != nil {
+		return
+	}
+	return exifString(tags["Make"]), exifString(tags["Model"]), nil
 }
 
 // GetFileInfo 获取文件信息
@@ -232,18 +142,161 @@ func RenameWithConflictResolution(oldPath, newPath string) (string, error) {
 		return oldPath, nil
 	}
 	// 检查目标文件是否存在，存在则加后缀
-	if _, err := os.Stat(newPath); !os.IsNotExist(err) {
-		base := strings.TrimSuffix(newPath, GetExt(newPath))
-		ext := GetExt(newPath)
-		counter := 1
-		for {
-			newPath = fmt.Sprintf("%s_%d%s", base, counter, ext)
-			if _, err := os.Stat(newPath); os.IsNotExist(err) {
-				break
-			}
-			counter++
+	newPath = ResolveConflictPath(newPath, func(path string) bool {
+		_, err := os.Stat(path)
+		return !os.IsNotExist(err) && !IsSameFile(oldPath, path)
+	})
+	// 执行重命名
+	return newPath, MoveFile(oldPath, newPath)
+}
+
+// 跨文件系统移动使用的文件操作,测试时可替换以模拟跨文件系统重命名及复制、删除失败
+var (
+	moveRename       = os.Rename
+	moveCopyFile     = copyFile
+	moveRemoveSource = os.Remove
+)
+
+// MoveFile 移动文件,跨文件系统无法直接重命名时复制并校验后删除原文件
+func MoveFile(oldPath, newPath string) error {
+	err := moveRename(oldPath, newPath)
+	if err == nil || !isCrossDeviceError(err) {
+		return err
+	}
+	if err = moveAcrossDevices(oldPath, newPath); err != nil {
+		return fmt.Errorf("跨文件系统移动失败,原文件未删除:%v", err)
+	}
+	return nil
+}
+
+// isCrossDeviceError 判断是否为跨文件系统重命名导致的错误
+func isCrossDeviceError(err error) bool {
+	return errors.Is(err, errCrossDevice)
+}
+
+// moveAcrossDevices 先复制到目标目录中的临时文件,写入磁盘并校验md5后再重命名为目标文件,最后删除原文件
+func moveAcrossDevices(oldPath, newPath string) error {
+	ctx := context.Background()
+	hash, err := GetFileHash(ctx, oldPath)
+	if err != nil {
+		return err
+	}
+	tmpPath := filepath.Join(filepath.Dir(newPath), fmt.Sprintf(".%s.%s.tmp", filepath.Base(newPath), hash[:8]))
+	if err = moveCopyFile(oldPath, tmpPath); err != nil {
+		if !errors.Is(err, os.ErrExist) {
+			_ = os.Remove(tmpPath)
 		}
+		return err
+	}
+	copyHash, err := GetFileHash(ctx, tmpPath)
+	if err == nil && copyHash != hash {
+		err = fmt.Errorf("复制后的文件md5(%s)与原文件(%s)不一致", copyHash, hash)
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, newPath)
+	}
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	if err = moveRemoveSource(oldPath); err != nil {
+		// 原文件无法删除时撤回复制,避免同一文件出现两份
+		_ = os.Remove(newPath)
+		return err
+	}
+	return nil
+}
+
+// copyFile 复制文件内容、权限及修改时间,写入磁盘后才返回,目标文件已存在时返回os.ErrExist
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_EXCL|os.O_WRONLY, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err = out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	if err = out.Close(); err != nil {
+		return err
+	}
+	// 创建文件时的权限受umask影响,需重新设置
+	if err = os.Chmod(dst, info.Mode().Perm()); err != nil {
+		return err
+	}
+	return os.Chtimes(dst, info.ModTime(), info.ModTime())
+}
+
+// CopyWithConflictResolution 复制文件并校验md5,处理重名情况,校验失败时删除复制出的文件
+func CopyWithConflictResolution(oldPath, newPath string) (string, error) {
+	newPath = ResolveConflictPath(newPath, func(path string) bool {
+		_, err := os.Stat(path)
+		return !os.IsNotExist(err)
+	})
+	// 复制及校验不受中断影响,避免留下不完整的文件
+	ctx := context.Background()
+	hash, err := GetFileHash(ctx, oldPath)
+	if err != nil {
+		return newPath, err
+	}
+	if err = copyFile(oldPath, newPath); err != nil {
+		// 目标文件已存在说明不是本次复制出的文件,不能删除
+		if !errors.Is(err, os.ErrExist) {
+			_ = os.Remove(newPath)
+		}
+		return newPath, err
+	}
+	copyHash, err := GetFileHash(ctx, newPath)
+	if err == nil && copyHash != hash {
+		err = fmt.Errorf("复制后的文件md5(%s)与原文件(%s)不一致", copyHash, hash)
+	}
+	if err != nil {
+		_ = os.Remove(newPath)
+		return newPath, err
+	}
+	return newPath, nil
+}
+
+// IsSameFile 判断两个路径是否指向同一文件,不区分大小写的文件系统中仅扩展名大小写不同的路径指向同一文件
+func IsSameFile(path1, path2 string) bool {
+	info1, err := os.Stat(path1)
+	if err != nil {
+		return false
+	}
+	info2, err := os.Stat(path2)
+	if err != nil {
+		return false
+	}
+	return os.SameFile(info1, info2)
+}
+
+// ResolveConflictPath 目标文件已存在时依次尝试添加_1/_2/_N后缀,返回首个不存在的路径
+func ResolveConflictPath(newPath string, exists func(path string) bool) string {
+	if !exists(newPath) {
+		return newPath
+	}
+	// 后缀加在扩展名之前,保留扩展名规范化后的大小写
+	ext := filepath.Ext(newPath)
+	base := strings.TrimSuffix(newPath, ext)
+	counter := 1
+	for {
+		newPath = fmt.Sprintf("%s_%d%s", base, counter, ext)
+		if !exists(newPath) {
+			return newPath
+		}
+		counter++
 	}
-	// 执行重命名
-	return newPath, os.Rename(oldPath, newPath)
 }
@@ -0,0 +1,75 @@
+package core
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMoveFileAcrossDevices(t *testing.T) {
+	tests := []struct {
+		name         string
+		copyFile     func(src, dst string) error
+		removeSource func(path string) error
+		wantErr      bool
+	}{
+		{"复制并删除原文件", copyFile, os.Remove, false},
+		{"复制后md5不一致", func(src, dst string) error {
+			if err := copyFile(src, dst); err != nil {
+				return err
+			}
+			return os.WriteFile(dst, []byte("corrupted"), 0644)
+		}, os.Remove, true},
+		{"原文件无法删除", copyFile, func(path string) error {
+			return &os.PathError{Op: "remove", Path: path, Err: os.ErrPermission}
+		}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 重命名总是返回跨文件系统错误,强制走复制流程
+			origRename, origCopy, origRemove := moveRename, moveCopyFile, moveRemoveSource
+			t.Cleanup(func() { moveRename, moveCopyFile, moveRemoveSource = origRename, origCopy, origRemove })
+			moveRename = func(oldPath, newPath string) error {
+				return &os.LinkError{Op: "rename", Old: oldPath, New: newPath, Err: errCrossDevice}
+			}
+			moveCopyFile, moveRemoveSource = tt.copyFile, tt.removeSource
+
+			srcDir, dstDir := t.TempDir(), t.TempDir()
+			oldPath, newPath := filepath.Join(srcDir, "photo.jpg"), filepath.Join(dstDir, "IMG_20240501_120000.JPG")
+			if err := os.WriteFile(oldPath, []byte("photo"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			err := MoveFile(oldPath, newPath)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("MoveFile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			// 失败时原文件保留且目标目录中不留下任何文件,成功时只有目标文件
+			_, oldErr := os.Stat(oldPath)
+			data, newErr := os.ReadFile(newPath)
+			if tt.wantErr {
+				if oldErr != nil || !os.IsNotExist(newErr) {
+					t.Errorf("source error = %v, target error = %v, want source kept and no target", oldErr, newErr)
+				}
+			} else if !os.IsNotExist(oldErr) || newErr != nil || string(data) != "photo" {
+				t.Errorf("source error = %v, target = %q, %v, want source removed and target copied", oldErr, data, newErr)
+			}
+			wantEntries := 1
+			if tt.wantErr {
+				wantEntries = 0
+			}
+			if entries, _ := os.ReadDir(dstDir); len(entries) != wantEntries {
+				t.Errorf("target dir has %d entries, want %d", len(entries), wantEntries)
+			}
+		})
+	}
+}
+
+func TestMoveFileRenameError(t *testing.T) {
+	// 非跨文件系统的重命名错误直接返回,不复制文件
+	dir := t.TempDir()
+	err := MoveFile(filepath.Join(dir, "missing.jpg"), filepath.Join(dir, "IMG_20240501_120000.JPG"))
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("MoveFile() error = %v, want os.ErrNotExist", err)
+	}
+}
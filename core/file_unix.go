@@ -0,0 +1,8 @@
+//go:build !windows
+
+package core
+
+import "syscall"
+
+// errCrossDevice 跨文件系统重命名时返回的错误
+const errCrossDevice = syscall.EXDEV
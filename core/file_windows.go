@@ -0,0 +1,8 @@
+//go:build windows
+
+package core
+
+import "syscall"
+
+// errCrossDevice ERROR_NOT_SAME_DEVICE,跨盘符移动文件时返回
+const errCrossDevice syscall.Errno = 17
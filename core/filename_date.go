@@ -0,0 +1,179 @@
+package core
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 文件名规则中可用的命名分组
+const (
+	filenameGroupYear   = "year"    // 四位年份
+	filenameGroupMonth  = "month"   // 月
+	filenameGroupDay    = "day"     // 日
+	filenameGroupHour   = "hour"    // 时,可选
+	filenameGroupMinute = "minute"  // 分,可选
+	filenameGroupSecond = "second"  // 秒,可选
+	filenameGroupMs     = "ms"      // 秒的小数部分,可选
+	filenameGroupUnix   = "unix"    // Unix时间戳(秒)
+	filenameGroupUnixMs = "unix_ms" // Unix时间戳(毫秒)
+)
+
+// FilenameRule 从文件名推断时间的规则,正则表达式匹配不含扩展名的文件名
+// 通过命名分组提取时间,需包含year、month、day(可选hour、minute、second、ms)或unix、unix_ms之一
+type FilenameRule struct {
+	Name    string `yaml:"name" json:"name"`         // 规则名称,记录在拍摄时间来源中
+	Pattern string `yaml:"pattern" json:"pattern"`   // 正则表达式
+	UTC     bool   `yaml:"utc" json:"utc,omitempty"` // 文件名中的时间为UTC时间,否则为拍摄地的当地时间
+	regexp  *regexp.Regexp
+}
+
+// DefaultFilenameRules 内置的文件名规则,始终排在自定义规则之后,具体的规则在前,通用的规则在后
+var DefaultFilenameRules = []*FilenameRule{
+	// 微信保存的图片及拍摄的视频,如mmexport1690000000000.jpg、wx_camera_1690000000000.mp4
+	{Name: "wechat", Pattern: `^(?:mmexport|wx_camera_)(?P<unix_ms>1\d{12})`},
+	// Pixel手机,文件名中为UTC时间,如PXL_20230101_101010123.jpg
+	{Name: "pixel", Pattern: `^PXL_(?P<year>\d{4})(?P<month>\d{2})(?P<day>\d{2})_(?P<hour>\d{2})(?P<minute>\d{2})(?P<second>\d{2})(?P<ms>\d{3})`, UTC: true},
+	// WhatsApp,只有日期,如IMG-20230101-WA0001.jpg
+	{Name: "whatsapp", Pattern: `^(?:IMG|VID|AUD|PTT|STK)-(?P<year>\d{4})(?P<month>\d{2})(?P<day>\d{2})-WA\d+`},
+	// Android截图,如Screenshot_20230101-101010.png、Screenshot_2023-01-01-10-10-10-123_com.app.png
+	{Name: "screenshot", Pattern: `(?i)^Screenshot_(?P<year>\d{4})-?(?P<month>\d{2})-?(?P<day>\d{2})[-_](?P<hour>\d{2})-?(?P<minute>\d{2})-?(?P<second>\d{2})`},
+	// 年月日时分秒之间可以有分隔符,如IMG_20250606_121601、2025-06-06 12.16.01、VID20250606121601
+	{Name: "datetime", Pattern: `(?:^|\D)(?P<year>(?:19|20)\d{2})[-_.]?(?P<month>\d{2})[-_.]?(?P<day>\d{2})[-_. T]?(?P<hour>\d{2})[-_.:]?(?P<minute>\d{2})[-_.:]?(?P<second>\d{2})(?:\D|$)`},
+	// 只有日期,时间为00:00:00,如2025-06-06
+	{Name: "date", Pattern: `(?:^|\D)(?P<year>(?:19|20)\d{2})[-_.]?(?P<month>\d{2})[-_.]?(?P<day>\d{2})(?:\D|$)`},
+}
+
+// ParseFilenameRule 解析命令行中的文件名规则,格式为 名称=正则表达式
+func ParseFilenameRule(spec string) (*FilenameRule, error) {
+	name, pattern, ok := strings.Cut(spec, "=")
+	if !ok || strings.TrimSpace(name) == "" || pattern == "" {
+		return nil, fmt.Errorf("文件名规则%s格式错误,正确格式如camera=^DSC_(?P<year>\\d{4})(?P<month>\\d{2})(?P<day>\\d{2})", spec)
+	}
+	return &FilenameRule{Name: strings.TrimSpace(name), Pattern: pattern}, nil
+}
+
+// ValidateFilenameRules 校验文件名规则的正则表达式及命名分组
+func ValidateFilenameRules(rules []*FilenameRule) error {
+	for _, rule := range rules {
+		if rule.Name == "" {
+			return fmt.Errorf("文件名规则%s缺少名称", rule.Pattern)
+		}
+		re, err := rule.compile()
+		if err != nil {
+			return fmt.Errorf("文件名规则%s的正则表达式错误:%v", rule.Name, err)
+		}
+		groups := make(map[string]bool)
+		for _, group := range re.SubexpNames() {
+			groups[group] = true
+		}
+		if !groups[filenameGroupUnix] && !groups[filenameGroupUnixMs] &&
+			!(groups[filenameGroupYear] && groups[filenameGroupMonth] && groups[filenameGroupDay]) {
+			return fmt.Errorf("文件名规则%s需要包含命名分组year、month、day,或unix、unix_ms之一", rule.Name)
+		}
+	}
+	return nil
+}
+
+// FilenameRules 返回自定义规则及内置规则,自定义规则优先
+func FilenameRules(custom []*FilenameRule) []*FilenameRule {
+	return append(append([]*FilenameRule{}, custom...), DefaultFilenameRules...)
+}
+
+// String 规则的文本形式,与命令行格式一致
+func (r *FilenameRule) String() string {
+	return r.Name + "=" + r.Pattern
+}
+
+// compile 编译正则表达式,编译结果缓存在规则中
+func (r *FilenameRule) compile() (*regexp.Regexp, error) {
+	if r.regexp != nil {
+		return r.regexp, nil
+	}
+	re, err := regexp.Compile(r.Pattern)
+	if err != nil {
+		return nil, err
+	}
+	r.regexp = re
+	return re, nil
+}
+
+// Match 从不含扩展名的文件名中推断时间,文件名中有多处匹配时使用首个有效的时间
+func (r *FilenameRule) Match(name string) *MetadataDate {
+	re, err := r.compile()
+	if err != nil {
+		return nil
+	}
+	for _, matches := range re.FindAllStringSubmatch(name, -1) {
+		parts := make(map[string]string)
+		for i, group := range re.SubexpNames() {
+			if group != "" {
+				parts[group] = matches[i]
+			}
+		}
+		if date := r.dateFromParts(parts); date != nil {
+			return date
+		}
+	}
+	return nil
+}
+
+// dateFromParts 由命名分组组成时间,超出范围时返回nil
+func (r *FilenameRule) dateFromParts(parts map[string]string) *MetadataDate {
+	if value := parts[filenameGroupUnixMs]; value != "" {
+		ms, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil
+		}
+		return &MetadataDate{Time: time.UnixMilli(ms), OffsetKnown: true}
+	}
+	if value := parts[filenameGroupUnix]; value != "" {
+		seconds, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil
+		}
+		return &MetadataDate{Time: time.Unix(seconds, 0), OffsetKnown: true}
+	}
+	values := make(map[string]int)
+	for _, group := range []string{filenameGroupYear, filenameGroupMonth, filenameGroupDay, filenameGroupHour, filenameGroupMinute, filenameGroupSecond} {
+		if parts[group] == "" {
+			continue
+		}
+		value, err := strconv.Atoi(parts[group])
+		if err != nil {
+			return nil
+		}
+		values[group] = value
+	}
+	year, month, day := values[filenameGroupYear], values[filenameGroupMonth], values[filenameGroupDay]
+	hour, minute, second := values[filenameGroupHour], values[filenameGroupMinute], values[filenameGroupSecond]
+	var ms int
+	if digits := parts[filenameGroupMs]; digits != "" {
+		ms, _ = strconv.Atoi((digits + "00")[:3])
+	}
+	location := time.Local
+	if r.UTC {
+		location = time.UTC
+	}
+	t := time.Date(year, time.Month(month), day, hour, minute, second, ms*int(time.Millisecond), location)
+	// 日期超出范围时time.Date会自动进位,此时视为无效
+	if year == 0 || t.Month() != time.Month(month) || t.Day() != day || hour > 23 || minute > 59 || second > 59 {
+		return nil
+	}
+	return &MetadataDate{Time: t, OffsetKnown: r.UTC}
+}
+
+// MatchFilenameDate 按顺序匹配规则,返回首个推断出的时间及规则名称,都不匹配时返回nil
+func MatchFilenameDate(rules []*FilenameRule, path string) (*MetadataDate, string) {
+	name := filepath.Base(path)
+	name = strings.TrimSuffix(name, filepath.Ext(name))
+	for _, rule := range rules {
+		if date := rule.Match(name); date != nil {
+			return date, rule.Name
+		}
+	}
+	return nil, ""
+}
@@ -0,0 +1,116 @@
+package core
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"os"
+
+	"github.com/thoas/go-funk"
+)
+
+// gifXMPApplication XMP应用扩展的标识及认证码
+const gifXMPApplication = "XMP DataXMP"
+
+// errNotGIF 文件不是GIF格式
+var errNotGIF = errors.New("不是有效的GIF文件")
+
+// IsGIF 判断文件是否为GIF图片
+func IsGIF(path string) bool {
+	return funk.ContainsString([]string{".GIF"}, GetExt(path))
+}
+
+// readGIFXMP 读取GIF应用扩展中的XMP,没有时返回nil
+func readGIFXMP(filename string) ([]byte, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	reader := bufio.NewReader(file)
+	// 文件头(6) + 逻辑屏幕描述符(7)
+	header := make([]byte, 13)
+	if _, err = io.ReadFull(reader, header); err != nil || (string(header[:6]) != "GIF87a" && string(header[:6]) != "GIF89a") {
+		return nil, errNotGIF
+	}
+	if err = skipGIFColorTable(reader, header[10]); err != nil {
+		return nil, nil
+	}
+	for {
+		introducer, err := reader.ReadByte()
+		if err != nil {
+			return nil, nil
+		}
+		switch introducer {
+		case 0x21:
+			// 扩展: 标签(1) + 数据子块
+			label, err := reader.ReadByte()
+			if err != nil {
+				return nil, nil
+			}
+			data, err := readGIFSubBlocks(reader, label == 0xff)
+			if err != nil {
+				return nil, nil
+			}
+			// XMP应用扩展中XMP直接写入,子块长度字节也是XMP的一部分
+			if label == 0xff && len(data) > 12 && data[0] == 11 && string(data[1:12]) == gifXMPApplication {
+				return FindXMPPacket(data[12:]), nil
+			}
+		case 0x2c:
+			// 图像描述符(9) + 局部颜色表 + LZW最小码长(1) + 图像数据子块
+			descriptor := make([]byte, 9)
+			if _, err = io.ReadFull(reader, descriptor); err != nil {
+				return nil, nil
+			}
+			if err = skipGIFColorTable(reader, descriptor[8]); err != nil {
+				return nil, nil
+			}
+			if _, err = reader.Discard(1); err != nil {
+				return nil, nil
+			}
+			if _, err = readGIFSubBlocks(reader, false); err != nil {
+				return nil, nil
+			}
+		default:
+			// 0x3b为文件结束
+			return nil, nil
+		}
+	}
+}
+
+// skipGIFColorTable 根据标志跳过颜色表
+func skipGIFColorTable(reader *bufio.Reader, flags byte) error {
+	if flags&0x80 == 0 {
+		return nil
+	}
+	_, err := reader.Discard(3 << (int(flags&0x07) + 1))
+	return err
+}
+
+// readGIFSubBlocks 读取数据子块直到长度为0的结束块,keep为true时返回包含长度字节的原始数据
+func readGIFSubBlocks(reader *bufio.Reader, keep bool) ([]byte, error) {
+	var data []byte
+	for {
+		size, err := reader.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		if size == 0 {
+			return data, nil
+		}
+		if !keep {
+			if _, err = reader.Discard(int(size)); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if len(data) > metadataMaxChunkSize {
+			return nil, errNotGIF
+		}
+		block := make([]byte, size)
+		if _, err = io.ReadFull(reader, block); err != nil {
+			return nil, err
+		}
+		data = append(append(data, size), block...)
+	}
+}
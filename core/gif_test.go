@@ -0,0 +1,70 @@
+package core
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// gifTestXMPExtension 构造XMP应用扩展,XMP之后为258字节的magic trailer
+func gifTestXMPExtension(xmp []byte) []byte {
+	extension := append([]byte{0x21, 0xff, 11}, gifXMPApplication...)
+	extension = append(append(extension, xmp...), 0x01)
+	for i := 0xff; i >= 0; i-- {
+		extension = append(extension, byte(i))
+	}
+	return append(extension, 0x00)
+}
+
+// buildGIF 构造带有全局颜色表的1x1 GIF,extensions位于图像之后
+func buildGIF(extensions ...[]byte) []byte {
+	data := append([]byte("GIF89a"), 1, 0, 1, 0, 0x80, 0, 0, 0, 0, 0, 0xff, 0xff, 0xff)
+	// 图形控制扩展及注释扩展
+	data = append(data, 0x21, 0xf9, 4, 0, 0, 0, 0, 0)
+	data = append(data, 0x21, 0xfe, 4, 't', 'e', 's', 't', 0)
+	data = append(data, 0x2c, 0, 0, 0, 0, 1, 0, 1, 0, 0, 0x02, 0x02, 0x44, 0x01, 0x00)
+	for _, extension := range extensions {
+		data = append(data, extension...)
+	}
+	return append(data, 0x3b)
+}
+
+func TestReadGIFXMP(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want []byte
+	}{
+		{"XMP应用扩展", buildGIF(gifTestXMPExtension(testXMP)), testXMP},
+		{"其他应用扩展之后的XMP", buildGIF(append([]byte{0x21, 0xff, 11}, "NETSCAPE2.0\x03\x01\x00\x00\x00"...), gifTestXMPExtension(testXMP)), testXMP},
+		{"没有XMP", buildGIF(), nil},
+		{"文件被截断", buildGIF(gifTestXMPExtension(testXMP))[:60], nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "image.gif")
+			if err := os.WriteFile(path, tt.data, 0644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := readGIFXMP(path)
+			if err != nil {
+				t.Fatalf("readGIFXMP() error = %v", err)
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("readGIFXMP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadGIFXMPNotGIF(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "image.gif")
+	if err := os.WriteFile(path, []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\x00\x00"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readGIFXMP(path); !errors.Is(err, errNotGIF) {
+		t.Errorf("readGIFXMP() error = %v, want %v", err, errNotGIF)
+	}
+}
@@ -0,0 +1,263 @@
+package core
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/thoas/go-funk"
+)
+
+// errNotHEIF 文件不是HEIF格式
+var errNotHEIF = errors.New("不是有效的HEIC/HEIF/AVIF文件")
+
+// IsHEIF 判断文件是否为HEIF(HEIC/HEIF/AVIF)格式的图片
+func IsHEIF(path string) bool {
+	return funk.ContainsString([]string{".HEIC", ".HEIF", ".AVIF"}, GetExt(path))
+}
+
+// heifExtent item数据在文件(或idat)中的一段
+type heifExtent struct {
+	offset int64
+	length int64
+}
+
+// heifLocation iloc中记录的item位置
+type heifLocation struct {
+	constructionMethod int // 0为文件偏移,1为idat中的偏移
+	extents            []heifExtent
+}
+
+// heifXMPContentType XMP item(类型为mime)的内容类型
+const heifXMPContentType = "application/rdf+xml"
+
+// GetHEIFExif 通过meta中的iinf/iloc找到Exif item,返回以TIFF头开始的EXIF数据,没有Exif item时返回nil
+func GetHEIFExif(filename string) ([]byte, error) {
+	data, err := readHEIFItemData(filename, func(itemType, contentType string) bool {
+		return itemType == "Exif"
+	})
+	if err != nil || data == nil {
+		return nil, err
+	}
+	// Exif item开头4字节为TIFF头的偏移,偏移之前通常为Exif\0\0
+	if len(data) < 4 {
+		return nil, errNotHEIF
+	}
+	tiffOffset := int64(binary.BigEndian.Uint32(data[:4])) + 4
+	if tiffOffset >= int64(len(data)) {
+		return nil, errNotHEIF
+	}
+	return data[tiffOffset:], nil
+}
+
+// GetHEIFXMP 通过meta中的iinf/iloc找到XMP item,没有时返回nil
+func GetHEIFXMP(filename string) ([]byte, error) {
+	return readHEIFItemData(filename, func(itemType, contentType string) bool {
+		return itemType == "mime" && contentType == heifXMPContentType
+	})
+}
+
+// readHEIFItemData 读取首个满足条件的item的数据,没有时返回nil
+func readHEIFItemData(filename string, match func(itemType, contentType string) bool) ([]byte, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	boxes, err := readISOBMFFBoxes(file, 0, info.Size())
+	if err != nil || len(boxes) == 0 || boxes[0].boxType != "ftyp" {
+		return nil, errNotHEIF
+	}
+	for _, box := range boxes {
+		if box.boxType == "meta" {
+			return readHEIFMetaItem(file, box, match)
+		}
+	}
+	return nil, errNotHEIF
+}
+
+// readHEIFMetaItem 在meta中查找满足条件的item并读取其数据
+func readHEIFMetaItem(r io.ReaderAt, meta *isobmffBox, match func(itemType, contentType string) bool) ([]byte, error) {
+	// meta为full box,子box前有4字节的版本及标志
+	if meta.size < 4 {
+		return nil, errNotHEIF
+	}
+	children, err := readISOBMFFBoxes(r, meta.offset+4, meta.size-4)
+	if err != nil {
+		return nil, errNotHEIF
+	}
+	var itemID uint32
+	var locations map[uint32]*heifLocation
+	var idat *isobmffBox
+	for _, child := range children {
+		switch child.boxType {
+		case "iinf":
+			data, err := readISOBMFFBox(r, child)
+			if err != nil {
+				return nil, err
+			}
+			itemID = findHEIFItem(r, child, data, match)
+		case "iloc":
+			data, err := readISOBMFFBox(r, child)
+			if err != nil {
+				return nil, err
+			}
+			if locations, err = parseHEIFItemLocations(data); err != nil {
+				return nil, err
+			}
+		case "idat":
+			idat = child
+		}
+	}
+	location := locations[itemID]
+	if itemID == 0 || location == nil {
+		return nil, nil
+	}
+	return readHEIFItem(r, location, idat)
+}
+
+// findHEIFItem 在iinf的infe中查找满足条件的item,返回其ID,没有时返回0
+func findHEIFItem(r io.ReaderAt, iinf *isobmffBox, data []byte, match func(itemType, contentType string) bool) uint32 {
+	if len(data) < 6 {
+		return 0
+	}
+	// 版本0的数量为2字节,其他版本为4字节
+	headerSize := int64(6)
+	if data[0] != 0 {
+		headerSize = 8
+	}
+	entries, err := readISOBMFFBoxes(r, iinf.offset+headerSize, iinf.size-headerSize)
+	if err != nil {
+		return 0
+	}
+	for _, entry := range entries {
+		if entry.boxType != "infe" {
+			continue
+		}
+		infe, err := readISOBMFFBox(r, entry)
+		if err != nil || len(infe) < 4 {
+			continue
+		}
+		// 版本2的item ID为2字节,版本3为4字节,之后为2字节的保护索引、4字节的类型及以\0结尾的名称,更早的版本没有类型
+		var itemID uint32
+		var rest []byte
+		switch version := infe[0]; {
+		case version == 2 && len(infe) >= 12:
+			itemID, rest = uint32(binary.BigEndian.Uint16(infe[4:6])), infe[8:]
+		case version == 3 && len(infe) >= 14:
+			itemID, rest = binary.BigEndian.Uint32(infe[4:8]), infe[10:]
+		default:
+			continue
+		}
+		itemType := string(rest[:4])
+		// 类型为mime时名称之后为以\0结尾的内容类型
+		var contentType string
+		if fields := bytes.SplitN(rest[4:], []byte{0}, 3); itemType == "mime" && len(fields) >= 2 {
+			contentType = string(fields[1])
+		}
+		if match(itemType, contentType) {
+			return itemID
+		}
+	}
+	return 0
+}
+
+// parseHEIFItemLocations 解析iloc,返回各item的位置
+func parseHEIFItemLocations(data []byte) (map[uint32]*heifLocation, error) {
+	if len(data) < 8 {
+		return nil, errNotHEIF
+	}
+	version := data[0]
+	offsetSize, lengthSize := int(data[4]>>4), int(data[4]&0x0f)
+	baseOffsetSize, indexSize := int(data[5]>>4), 0
+	if version == 1 || version == 2 {
+		indexSize = int(data[5] & 0x0f)
+	}
+	// 版本2的数量及item ID为4字节,其他版本为2字节
+	idSize := 2
+	if version == 2 {
+		idSize = 4
+	}
+	reader := &heifFieldReader{data: data, offset: 6}
+	itemCount := reader.read(idSize)
+	locations := make(map[uint32]*heifLocation)
+	for i := uint64(0); i < itemCount && reader.err == nil; i++ {
+		itemID := reader.read(idSize)
+		location := &heifLocation{}
+		if version == 1 || version == 2 {
+			location.constructionMethod = int(reader.read(2) & 0x0f)
+		}
+		reader.read(2) // data_reference_index
+		baseOffset := int64(reader.read(baseOffsetSize))
+		extentCount := reader.read(2)
+		for j := uint64(0); j < extentCount && reader.err == nil; j++ {
+			reader.read(indexSize)
+			offset := int64(reader.read(offsetSize))
+			length := int64(reader.read(lengthSize))
+			location.extents = append(location.extents, heifExtent{offset: baseOffset + offset, length: length})
+		}
+		locations[uint32(itemID)] = location
+	}
+	if reader.err != nil {
+		return nil, reader.err
+	}
+	return locations, nil
+}
+
+// readHEIFItem 读取item的全部数据
+func readHEIFItem(r io.ReaderAt, location *heifLocation, idat *isobmffBox) ([]byte, error) {
+	var base int64
+	switch location.constructionMethod {
+	case 0:
+	case 1:
+		if idat == nil {
+			return nil, errNotHEIF
+		}
+		base = idat.offset
+	default:
+		return nil, fmt.Errorf("不支持的HEIF item构造方式%d", location.constructionMethod)
+	}
+	var data []byte
+	for _, extent := range location.extents {
+		if extent.length <= 0 || int64(len(data))+extent.length > isobmffMaxBoxSize {
+			return nil, errNotHEIF
+		}
+		buf := make([]byte, extent.length)
+		if _, err := r.ReadAt(buf, base+extent.offset); err != nil {
+			return nil, err
+		}
+		data = append(data, buf...)
+	}
+	return data, nil
+}
+
+// heifFieldReader 按字节数读取大端整数,越界时记录错误
+type heifFieldReader struct {
+	data   []byte
+	offset int
+	err    error
+}
+
+// read 读取size字节的无符号整数,size为0时返回0
+func (r *heifFieldReader) read(size int) uint64 {
+	if r.err != nil || size == 0 {
+		return 0
+	}
+	if size > 8 || r.offset+size > len(r.data) {
+		r.err = errNotHEIF
+		return 0
+	}
+	var value uint64
+	for _, b := range r.data[r.offset : r.offset+size] {
+		value = value<<8 | uint64(b)
+	}
+	r.offset += size
+	return value
+}
@@ -0,0 +1,188 @@
+package core
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// heifTestLayout 测试HEIF文件中iinf/iloc的版本及字段长度
+type heifTestLayout struct {
+	infeVersion    byte // infe版本,2或3
+	ilocVersion    byte // iloc版本,0、1或2
+	offsetSize     int  // extent偏移的字节数
+	lengthSize     int  // extent长度的字节数
+	baseOffsetSize int  // 基础偏移的字节数,为0时extent偏移为绝对偏移
+	idat           bool // item数据位于idat中(构造方式1)
+	split          bool // 每个item的数据分为两段
+	noExif         bool // 不包含Exif item
+}
+
+// heifTestUint 按字节数写入大端整数
+func heifTestUint(size int, value uint64) []byte {
+	data := make([]byte, 8)
+	binary.BigEndian.PutUint64(data, value)
+	return data[8-size:]
+}
+
+// buildHEIF 构造包含图片、Exif及XMP item的HEIC文件,exif为以TIFF头开始的EXIF数据
+func buildHEIF(layout heifTestLayout, exif, xmp []byte) []byte {
+	exifItem := append([]byte{0, 0, 0, 6}, "Exif\x00\x00"...)
+	exifItem = append(exifItem, exif...)
+	type item struct {
+		id          uint64
+		itemType    string
+		contentType string
+		data        []byte
+	}
+	items := []item{{1, "hvc1", "", []byte("image")}, {2, "Exif", "", exifItem}, {3, "mime", heifXMPContentType, xmp}}
+	if layout.noExif {
+		items = append(items[:1], items[2])
+	}
+	idSize := 2
+	if layout.ilocVersion == 2 {
+		idSize = 4
+	}
+	ftyp := isobmffTestBox("ftyp", []byte("heic\x00\x00\x00\x00mif1heic"))
+	var payload []byte
+	for _, it := range items {
+		payload = append(payload, it.data...)
+	}
+	// dataStart为item数据在文件(或idat)中的起始位置,先以0构造meta计算长度
+	buildMeta := func(dataStart uint64) []byte {
+		var infes [][]byte
+		for _, it := range items {
+			infe := []byte{layout.infeVersion, 0, 0, 0}
+			if layout.infeVersion == 3 {
+				infe = append(infe, heifTestUint(4, it.id)...)
+			} else {
+				infe = append(infe, heifTestUint(2, it.id)...)
+			}
+			infe = append(append(append(infe, 0, 0), it.itemType...), 0)
+			if it.contentType != "" {
+				infe = append(append(infe, it.contentType...), 0)
+			}
+			infes = append(infes, isobmffTestBox("infe", infe))
+		}
+		iinf := isobmffTestBox("iinf", append([]byte{0, 0, 0, 0, 0, byte(len(items))}, bytes.Join(infes, nil)...))
+		iloc := []byte{layout.ilocVersion, 0, 0, 0, byte(layout.offsetSize<<4 | layout.lengthSize), byte(layout.baseOffsetSize << 4)}
+		iloc = append(iloc, heifTestUint(idSize, uint64(len(items)))...)
+		offset := uint64(0)
+		for _, it := range items {
+			iloc = append(iloc, heifTestUint(idSize, it.id)...)
+			if layout.ilocVersion != 0 {
+				method := uint64(0)
+				if layout.idat {
+					method = 1
+				}
+				iloc = append(iloc, heifTestUint(2, method)...)
+			}
+			iloc = append(iloc, 0, 0)
+			// 有基础偏移时以其记录item的起始位置,extent偏移从0开始
+			start := dataStart + offset
+			if layout.baseOffsetSize > 0 {
+				iloc = append(iloc, heifTestUint(layout.baseOffsetSize, start)...)
+				start = 0
+			}
+			extents := [][2]uint64{{start, uint64(len(it.data))}}
+			if layout.split {
+				half := uint64(len(it.data) / 2)
+				extents = [][2]uint64{{start, half}, {start + half, uint64(len(it.data)) - half}}
+			}
+			iloc = append(iloc, heifTestUint(2, uint64(len(extents)))...)
+			for _, extent := range extents {
+				iloc = append(iloc, heifTestUint(layout.offsetSize, extent[0])...)
+				iloc = append(iloc, heifTestUint(layout.lengthSize, extent[1])...)
+			}
+			offset += uint64(len(it.data))
+		}
+		children := [][]byte{{0, 0, 0, 0}, isobmffTestBox("hdlr", make([]byte, 24)), iinf, isobmffTestBox("iloc", iloc)}
+		if layout.idat {
+			children = append(children, isobmffTestBox("idat", payload))
+		}
+		return isobmffTestBox("meta", children...)
+	}
+	if layout.idat {
+		return append(ftyp, buildMeta(0)...)
+	}
+	// mdat的8字节头之后为item数据
+	dataStart := uint64(len(ftyp) + len(buildMeta(0)) + 8)
+	return append(append(ftyp, buildMeta(dataStart)...), isobmffTestBox("mdat", payload)...)
+}
+
+func TestGetHEIFExif(t *testing.T) {
+	exif := buildTIFF(binary.BigEndian, 42, map[uint16]string{0x010f: "Apple"}, testRawExif)
+	xmp := []byte(`<x:xmpmeta xmlns:x="adobe:ns:meta/"><rdf:RDF><rdf:Description xmp:CreateDate="2023-04-05T06:07:08"/></rdf:RDF></x:xmpmeta>`)
+	tests := []struct {
+		name   string
+		layout heifTestLayout
+	}{
+		{"版本0的iloc及4字节偏移", heifTestLayout{infeVersion: 2, ilocVersion: 0, offsetSize: 4, lengthSize: 4}},
+		{"8字节偏移及基础偏移", heifTestLayout{infeVersion: 2, ilocVersion: 1, offsetSize: 8, lengthSize: 8, baseOffsetSize: 8}},
+		{"4字节基础偏移及0字节偏移", heifTestLayout{infeVersion: 2, ilocVersion: 1, offsetSize: 0, lengthSize: 4, baseOffsetSize: 4}},
+		{"版本2的iloc及版本3的infe", heifTestLayout{infeVersion: 3, ilocVersion: 2, offsetSize: 4, lengthSize: 4}},
+		{"数据位于idat中", heifTestLayout{infeVersion: 2, ilocVersion: 1, offsetSize: 4, lengthSize: 4, idat: true}},
+		{"数据分为两段", heifTestLayout{infeVersion: 2, ilocVersion: 1, offsetSize: 4, lengthSize: 4, baseOffsetSize: 4, split: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := buildHEIF(tt.layout, exif, xmp)
+			path := filepath.Join(t.TempDir(), "IMG_0001.HEIC")
+			if err := os.WriteFile(path, data, 0644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := GetHEIFExif(path)
+			if err != nil {
+				t.Fatalf("GetHEIFExif() error = %v", err)
+			}
+			if !bytes.Equal(got, exif) {
+				t.Errorf("GetHEIFExif() = %x, want %x", got, exif)
+			}
+			if got, err = GetHEIFXMP(path); err != nil || !bytes.Equal(got, xmp) {
+				t.Errorf("GetHEIFXMP() = %q, %v, want %q", got, err, xmp)
+			}
+			assertRawCaptureTime(t, path, "Apple")
+		})
+	}
+}
+
+func TestGetHEIFExifMissing(t *testing.T) {
+	layout := heifTestLayout{infeVersion: 2, ilocVersion: 1, offsetSize: 4, lengthSize: 4, noExif: true}
+	path := filepath.Join(t.TempDir(), "IMG_0001.HEIC")
+	if err := os.WriteFile(path, buildHEIF(layout, nil, []byte("<x:xmpmeta/>")), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if exif, err := GetHEIFExif(path); exif != nil || err != nil {
+		t.Errorf("GetHEIFExif() = %x, %v, want nil, nil", exif, err)
+	}
+}
+
+func TestParseHEIFItemLocations(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		want    map[uint32]*heifLocation
+		wantErr bool
+	}{
+		{"版本1的构造方式", []byte{1, 0, 0, 0, 0x44, 0x00, 0, 1, 0, 7, 0, 1, 0, 0, 0, 1, 0, 0, 0, 16, 0, 0, 0, 32},
+			map[uint32]*heifLocation{7: {constructionMethod: 1, extents: []heifExtent{{16, 32}}}}, false},
+		{"数据被截断", []byte{0, 0, 0, 0, 0x44, 0x00, 0, 1, 0, 7, 0, 0, 0, 1, 0, 0}, nil, true},
+		{"长度不足", []byte{0, 0, 0, 0}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseHEIFItemLocations(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseHEIFItemLocations() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			for id, want := range tt.want {
+				if location := got[id]; location == nil || location.constructionMethod != want.constructionMethod ||
+					len(location.extents) != len(want.extents) || location.extents[0] != want.extents[0] {
+					t.Errorf("location[%d] = %+v, want %+v", id, location, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,333 @@
+package core
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/thoas/go-funk"
+)
+
+// isobmffMaxBoxSize 需要完整读取的box的最大长度,防止异常文件占用过多内存
+const isobmffMaxBoxSize = 4 << 20
+
+// isobmffEpoch mvhd/mdhd中的时间为自1904-01-01 UTC起的秒数
+var isobmffEpoch = time.Date(1904, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// QuickTime元数据中用到的键名
+const (
+	isobmffCreationDateKey = "com.apple.quicktime.creationdate"     // 拍摄时间
+	isobmffMakeKey         = "com.apple.quicktime.make"             // 相机厂商
+	isobmffModelKey        = "com.apple.quicktime.model"            // 相机型号
+	isobmffLocationKey     = "com.apple.quicktime.location.ISO6709" // 拍摄地点
+)
+
+// errNotISOBMFF 文件不是ISO-BMFF格式
+var errNotISOBMFF = errors.New("不是有效的MP4/MOV文件")
+
+// IsISOBMFF 判断文件是否为ISO-BMFF(MP4/MOV等)格式的视频
+func IsISOBMFF(path string) bool {
+	extensions := []string{".MP4", ".MOV", ".M4V", ".3GP", ".3G2", ".QT"}
+	return funk.ContainsString(extensions, GetExt(path))
+}
+
+// isobmffBox box的类型及内容在文件中的位置
+type isobmffBox struct {
+	boxType string
+	offset  int64 // 内容的起始位置,不含box头
+	size    int64 // 内容的长度
+}
+
+// isobmffInfo 解析出的各项信息,时间为零值表示没有该时间
+type isobmffInfo struct {
+	creationDate        time.Time     // QuickTime元数据中的拍摄时间
+	creationOffsetKnown bool          // 拍摄时间是否带有时区
+	movie               time.Time     // mvhd创建时间
+	media               time.Time     // 首个有效的mdhd创建时间
+	duration            time.Duration // mvhd中的时长
+	make                string        // QuickTime元数据中的相机厂商
+	model               string        // QuickTime元数据中的相机型号
+	gps                 *GPS          // QuickTime元数据中的拍摄地点
+}
+
+// GetISOBMFFMetadata 读取MP4/MOV文件的元数据,拍摄时间优先使用QuickTime元数据中的拍摄时间,其次为mvhd、mdhd的创建时间
+func GetISOBMFFMetadata(filename string) (*Metadata, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	boxes, err := readISOBMFFBoxes(file, 0, info.Size())
+	if err != nil {
+		return nil, err
+	}
+	if len(boxes) == 0 || !funk.ContainsString([]string{"ftyp", "moov", "mdat", "wide", "free", "skip"}, boxes[0].boxType) {
+		return nil, errNotISOBMFF
+	}
+	parsed := &isobmffInfo{}
+	for _, box := range boxes {
+		if box.boxType == "moov" {
+			if err = parseISOBMFFMoov(file, box, parsed); err != nil {
+				return nil, err
+			}
+		}
+	}
+	metadata := &Metadata{Make: parsed.make, Model: parsed.model, GPS: parsed.gps, Duration: parsed.duration}
+	// 与mediainfo一致,QuickTime拍摄时间为Recorded_Date,mvhd为Encoded_Date,mdhd为Tagged_Date
+	metadata.setDate(DateSourceVideoRecorded, parsed.creationDate, parsed.creationOffsetKnown)
+	metadata.setDate(DateSourceVideoEncoded, parsed.movie, true)
+	metadata.setDate(DateSourceVideoTagged, parsed.media, true)
+	switch {
+	case !parsed.creationDate.IsZero():
+		metadata.CaptureTime, metadata.OffsetKnown = parsed.creationDate, parsed.creationOffsetKnown
+	case !parsed.movie.IsZero():
+		metadata.CaptureTime, metadata.OffsetKnown = parsed.movie, true
+	case !parsed.media.IsZero():
+		metadata.CaptureTime, metadata.OffsetKnown = parsed.media, true
+	}
+	return metadata, nil
+}
+
+// readISOBMFFBoxes 读取指定范围内的全部box头
+func readISOBMFFBoxes(r io.ReaderAt, offset, size int64) ([]*isobmffBox, error) {
+	var boxes []*isobmffBox
+	end := offset + size
+	header := make([]byte, 16)
+	for offset+8 <= end {
+		if _, err := r.ReadAt(header[:8], offset); err != nil {
+			return nil, err
+		}
+		boxSize := int64(binary.BigEndian.Uint32(header[:4]))
+		boxType := string(header[4:8])
+		headerSize := int64(8)
+		switch boxSize {
+		case 0:
+			// 长度为0表示box延续到末尾
+			boxSize = end - offset
+		case 1:
+			if _, err := r.ReadAt(header[8:16], offset+8); err != nil {
+				return nil, err
+			}
+			boxSize = int64(binary.BigEndian.Uint64(header[8:16]))
+			headerSize = 16
+		}
+		if boxSize < headerSize || offset+boxSize > end {
+			return nil, errNotISOBMFF
+		}
+		boxes = append(boxes, &isobmffBox{boxType: boxType, offset: offset + headerSize, size: boxSize - headerSize})
+		offset += boxSize
+	}
+	return boxes, nil
+}
+
+// readISOBMFFBox 读取box的完整内容
+func readISOBMFFBox(r io.ReaderAt, box *isobmffBox) ([]byte, error) {
+	if box.size > isobmffMaxBoxSize {
+		return nil, fmt.Errorf("%s box过大", box.boxType)
+	}
+	data := make([]byte, box.size)
+	_, err := r.ReadAt(data, box.offset)
+	return data, err
+}
+
+// parseISOBMFFMoov 解析moov中的mvhd、trak/mdia/mdhd及meta
+func parseISOBMFFMoov(r io.ReaderAt, moov *isobmffBox, parsed *isobmffInfo) error {
+	boxes, err := readISOBMFFBoxes(r, moov.offset, moov.size)
+	if err != nil {
+		return err
+	}
+	for _, box := range boxes {
+		switch box.boxType {
+		case "mvhd":
+			data, err := readISOBMFFBox(r, box)
+			if err != nil {
+				return err
+			}
+			parsed.movie = parseISOBMFFHeaderTime(data)
+			parsed.duration = parseISOBMFFDuration(data)
+		case "trak":
+			if parsed.media.IsZero() {
+				if parsed.media, err = findISOBMFFMediaTime(r, box); err != nil {
+					return err
+				}
+			}
+		case "meta":
+			if err = parseISOBMFFMeta(r, box, parsed); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// parseISOBMFFHeaderTime 解析mvhd/mdhd中的创建时间,时间为UTC,未设置时返回零值
+func parseISOBMFFHeaderTime(data []byte) time.Time {
+	if len(data) < 8 {
+		return time.Time{}
+	}
+	var seconds uint64
+	if data[0] == 1 {
+		if len(data) < 12 {
+			return time.Time{}
+		}
+		seconds = binary.BigEndian.Uint64(data[4:12])
+	} else {
+		seconds = uint64(binary.BigEndian.Uint32(data[4:8]))
+	}
+	if seconds == 0 {
+		return time.Time{}
+	}
+	return isobmffEpoch.Add(time.Duration(seconds) * time.Second)
+}
+
+// parseISOBMFFDuration 解析mvhd中的时长,时长以timescale为单位
+func parseISOBMFFDuration(data []byte) time.Duration {
+	var timescale, duration uint64
+	switch {
+	case len(data) >= 32 && data[0] == 1:
+		timescale, duration = uint64(binary.BigEndian.Uint32(data[20:24])), binary.BigEndian.Uint64(data[24:32])
+	case len(data) >= 20 && data[0] == 0:
+		timescale, duration = uint64(binary.BigEndian.Uint32(data[12:16])), uint64(binary.BigEndian.Uint32(data[16:20]))
+	}
+	if timescale == 0 {
+		return 0
+	}
+	return time.Duration(float64(duration) / float64(timescale) * float64(time.Second))
+}
+
+// findISOBMFFMediaTime 查找trak/mdia/mdhd中的创建时间
+func findISOBMFFMediaTime(r io.ReaderAt, trak *isobmffBox) (time.Time, error) {
+	boxes, err := readISOBMFFBoxes(r, trak.offset, trak.size)
+	if err != nil {
+		return time.Time{}, err
+	}
+	for _, box := range boxes {
+		if box.boxType != "mdia" {
+			continue
+		}
+		children, err := readISOBMFFBoxes(r, box.offset, box.size)
+		if err != nil {
+			return time.Time{}, err
+		}
+		for _, child := range children {
+			if child.boxType != "mdhd" {
+				continue
+			}
+			data, err := readISOBMFFBox(r, child)
+			if err != nil {
+				return time.Time{}, err
+			}
+			return parseISOBMFFHeaderTime(data), nil
+		}
+	}
+	return time.Time{}, nil
+}
+
+// parseISOBMFFMeta 读取QuickTime元数据(meta/keys/ilst)中的拍摄时间、相机及拍摄地点
+func parseISOBMFFMeta(r io.ReaderAt, meta *isobmffBox, parsed *isobmffInfo) error {
+	// MP4中的meta带有version/flags,QuickTime中的meta没有,据此判断子box的起始位置
+	peek := make([]byte, 8)
+	if meta.size < 8 {
+		return nil
+	}
+	if _, err := r.ReadAt(peek, meta.offset); err != nil {
+		return err
+	}
+	offset, size := meta.offset, meta.size
+	if string(peek[4:8]) != "hdlr" {
+		offset, size = offset+4, size-4
+	}
+	boxes, err := readISOBMFFBoxes(r, offset, size)
+	if err != nil {
+		return err
+	}
+	var keys []string
+	var ilst *isobmffBox
+	for _, box := range boxes {
+		switch box.boxType {
+		case "keys":
+			data, err := readISOBMFFBox(r, box)
+			if err != nil {
+				return err
+			}
+			keys = parseISOBMFFKeys(data)
+		case "ilst":
+			ilst = box
+		}
+	}
+	if ilst == nil {
+		return nil
+	}
+	items, err := readISOBMFFBoxes(r, ilst.offset, ilst.size)
+	if err != nil {
+		return err
+	}
+	for _, item := range items {
+		// ilst中子box的类型为keys中从1开始的序号
+		index := int(binary.BigEndian.Uint32([]byte(item.boxType)))
+		if index < 1 || index > len(keys) {
+			continue
+		}
+		key := keys[index-1]
+		if !funk.ContainsString([]string{isobmffCreationDateKey, isobmffMakeKey, isobmffModelKey, isobmffLocationKey}, key) {
+			continue
+		}
+		data, err := readISOBMFFBox(r, item)
+		if err != nil {
+			return err
+		}
+		// data box: 长度(4) + "data"(4) + 类型(4) + 语言(4) + 值
+		if len(data) < 16 || string(data[4:8]) != "data" {
+			continue
+		}
+		value := strings.TrimRight(string(data[16:]), "\x00")
+		switch key {
+		case isobmffCreationDateKey:
+			// 无法解析或为占位值时视为没有拍摄时间,仍使用mvhd/mdhd中的时间
+			parsed.creationDate, parsed.creationOffsetKnown = parseISOBMFFCreationDate(value)
+		case isobmffMakeKey:
+			parsed.make = value
+		case isobmffModelKey:
+			parsed.model = value
+		case isobmffLocationKey:
+			parsed.gps = parseISO6709(value)
+		}
+	}
+	return nil
+}
+
+// parseISOBMFFKeys 解析keys中的键名
+func parseISOBMFFKeys(data []byte) []string {
+	if len(data) < 8 {
+		return nil
+	}
+	count := binary.BigEndian.Uint32(data[4:8])
+	keys := make([]string, 0, count)
+	for offset := 8; offset+8 <= len(data) && uint32(len(keys)) < count; {
+		size := int(binary.BigEndian.Uint32(data[offset : offset+4]))
+		if size < 8 || offset+size > len(data) {
+			break
+		}
+		keys = append(keys, string(data[offset+8:offset+size]))
+		offset += size
+	}
+	return keys
+}
+
+// parseISOBMFFCreationDate 解析QuickTime拍摄时间,如2024-05-02T03:04:05+0800,保留拍摄地的当地时间,返回是否带有时区
+// 无法解析或为占位值时返回零值
+func parseISOBMFFCreationDate(value string) (time.Time, bool) {
+	date, err := ParseDateTime(value, time.Local)
+	if err != nil || date == nil {
+		return time.Time{}, false
+	}
+	return date.Time, date.OffsetKnown
+}
@@ -0,0 +1,65 @@
+package core
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// buildQuickTime 构造带有mvhd及QuickTime拍摄时间的MOV文件,creationDate为空时不写入meta
+func buildQuickTime(movie time.Time, creationDate string) []byte {
+	mvhd := make([]byte, 20)
+	binary.BigEndian.PutUint32(mvhd[4:], uint32(movie.Sub(isobmffEpoch)/time.Second))
+	children := [][]byte{isobmffTestBox("mvhd", mvhd)}
+	if creationDate != "" {
+		key := isobmffTestBox("mdta", []byte(isobmffCreationDateKey))
+		keys := make([]byte, 8)
+		binary.BigEndian.PutUint32(keys[4:], 1)
+		value := isobmffTestBox("data", make([]byte, 8), []byte(creationDate))
+		item := isobmffTestBox("\x00\x00\x00\x01", value)
+		children = append(children, isobmffTestBox("meta",
+			isobmffTestBox("hdlr", make([]byte, 24)),
+			isobmffTestBox("keys", keys, key),
+			isobmffTestBox("ilst", item),
+		))
+	}
+	return append(isobmffTestBox("ftyp", []byte("qt  \x00\x00\x00\x00qt  ")), isobmffTestBox("moov", children...)...)
+}
+
+func TestGetISOBMFFMetadataCreationDate(t *testing.T) {
+	movie := time.Date(2024, 5, 1, 19, 4, 5, 0, time.UTC)
+	tests := []struct {
+		name         string
+		creationDate string
+		want         time.Time
+		wantRecorded bool
+	}{
+		{"使用QuickTime拍摄时间", "2024-05-02T03:04:05+0800", time.Date(2024, 5, 2, 3, 4, 5, 0, time.FixedZone("", 8*3600)), true},
+		{"无法解析时使用mvhd", "not a date", movie, false},
+		{"占位值时使用mvhd", "0000-00-00T00:00:00Z", movie, false},
+		{"没有QuickTime拍摄时间", "", movie, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "video.mov")
+			if err := os.WriteFile(path, buildQuickTime(movie, tt.creationDate), 0644); err != nil {
+				t.Fatal(err)
+			}
+			metadata, err := GetISOBMFFMetadata(path)
+			if err != nil {
+				t.Fatalf("GetISOBMFFMetadata() error = %v", err)
+			}
+			if !metadata.CaptureTime.Equal(tt.want) {
+				t.Errorf("CaptureTime = %v, want %v", metadata.CaptureTime, tt.want)
+			}
+			if recorded := metadata.Dates[DateSourceVideoRecorded] != nil; recorded != tt.wantRecorded {
+				t.Errorf("Dates[%s] present = %v, want %v", DateSourceVideoRecorded, recorded, tt.wantRecorded)
+			}
+			if date := metadata.Dates[DateSourceVideoEncoded]; date == nil || !date.Time.Equal(movie) {
+				t.Errorf("Dates[%s] = %v, want %v", DateSourceVideoEncoded, date, movie)
+			}
+		})
+	}
+}
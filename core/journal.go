@@ -0,0 +1,291 @@
+package core
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// AppDir 用户目录下保存运行数据的文件夹
+const AppDir = ".go-rename"
+
+// JournalFileName 重命名日志文件名
+const JournalFileName = "journal.jsonl"
+
+// 日志记录的操作类型
+const (
+	JournalOpMove    = "move"    // 移动文件,目标已存在时添加_N后缀
+	JournalOpReplace = "replace" // 移动文件并覆盖目标文件
+	JournalOpCopy    = "copy"    // 复制文件
+	JournalOpDelete  = "delete"  // 删除附属文件,记录文件内容用于撤销
+	JournalOpStart   = "start"   // 运行开始,记录运行选项
+	JournalOpDone    = "done"    // 文件处理完成(包括未移动的文件)
+	JournalOpFinish  = "finish"  // 运行正常结束
+)
+
+// JournalEntry 重命名日志记录
+type JournalEntry struct {
+	RunID   string   `json:"run_id"`             // 运行ID
+	Time    string   `json:"time"`               // 操作时间
+	Op      string   `json:"op"`                 // 操作类型
+	OldPath string   `json:"old_path,omitempty"` // 原路径
+	NewPath string   `json:"new_path,omitempty"` // 新路径
+	Hash    string   `json:"hash,omitempty"`     // 文件md5,仅删除记录,之前版本的日志中所有操作都有
+	Size    int64    `json:"size,omitempty"`     // 文件大小,与修改时间一起用于核对文件
+	ModTime string   `json:"mod_time,omitempty"` // 文件修改时间
+	Pending bool     `json:"pending,omitempty"`  // 操作执行前写入的待完成记录,操作完成后另有完整记录
+	Source  string   `json:"source,omitempty"`   // 拍摄时间的来源,仅done记录
+	Data    []byte   `json:"data,omitempty"`     // 删除的文件内容,仅delete记录
+	Options *Options `json:"options,omitempty"`  // 运行选项,仅start记录
+}
+
+// IsFileOp 是否为实际修改文件的操作,不包括待完成记录
+func (e *JournalEntry) IsFileOp() bool {
+	return !e.Pending && (e.Op == JournalOpMove || e.Op == JournalOpReplace || e.Op == JournalOpCopy || e.Op == JournalOpDelete)
+}
+
+// MatchesFile 判断文件是否与记录一致,记录了大小及修改时间时按其比较,否则比较md5
+func (e *JournalEntry) MatchesFile(ctx context.Context, path string) (bool, error) {
+	if e.ModTime == "" {
+		hash, err := GetFileHash(ctx, path)
+		return hash == e.Hash, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return false, err
+	}
+	modTime, err := time.Parse(time.RFC3339Nano, e.ModTime)
+	if err != nil {
+		return false, err
+	}
+	// FAT等文件系统的修改时间精度为2秒,跨文件系统移动后可能被截断
+	diff := info.ModTime().Sub(modTime)
+	return info.Size() == e.Size && diff > -2*time.Second && diff < 2*time.Second, nil
+}
+
+// Journal 只追加写入的重命名日志,每个操作写入一行JSON
+type Journal struct {
+	RunID string
+	file  *os.File
+}
+
+// DefaultJournalPath 默认的日志文件路径
+func DefaultJournalPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, AppDir, JournalFileName), nil
+}
+
+// NewRunID 生成运行ID,格式为时间加随机后缀
+func NewRunID() string {
+	suffix := make([]byte, 3)
+	_, _ = rand.Read(suffix)
+	return time.Now().Format("20060102-150405") + "-" + hex.EncodeToString(suffix)
+}
+
+// OpenJournal 打开日志文件,不存在时自动创建
+func OpenJournal(path, runID string) (*Journal, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &Journal{RunID: runID, file: file}, nil
+}
+
+// Append 追加一条文件操作日志记录,同时记录新文件的大小及修改时间,撤销时据此判断文件是否被修改
+func (j *Journal) Append(op, oldPath, newPath string) error {
+	entry := &JournalEntry{Op: op, OldPath: oldPath, NewPath: newPath}
+	if newPath != "" {
+		info, err := os.Stat(newPath)
+		if err != nil {
+			return err
+		}
+		entry.Size, entry.ModTime = info.Size(), info.ModTime().Format(time.RFC3339Nano)
+	}
+	return j.write(entry)
+}
+
+// Pending 在文件操作执行前追加一条待完成记录,同时记录原文件的大小及修改时间,
+// 进程在操作完成后、写入完整记录前崩溃时,撤销及继续运行据此核对操作是否已完成
+func (j *Journal) Pending(op, oldPath, newPath string) error {
+	info, err := os.Stat(oldPath)
+	if err != nil {
+		return err
+	}
+	return j.write(&JournalEntry{Op: op, OldPath: oldPath, NewPath: newPath, Size: info.Size(),
+		ModTime: info.ModTime().Format(time.RFC3339Nano), Pending: true})
+}
+
+// PendingDelete 在删除文件前追加一条待完成记录,data为删除前的文件内容,删除后的完整记录不再重复写入内容
+func (j *Journal) PendingDelete(oldPath, hash string, data []byte) error {
+	return j.write(&JournalEntry{Op: JournalOpDelete, OldPath: oldPath, Hash: hash, Data: data, Pending: true})
+}
+
+// Start 记录运行开始及运行选项
+func (j *Journal) Start(opts *Options) error {
+	return j.write(&JournalEntry{Op: JournalOpStart, Options: opts})
+}
+
+// Finish 记录运行正常结束
+func (j *Journal) Finish() error {
+	return j.write(&JournalEntry{Op: JournalOpFinish})
+}
+
+// write 写入一行日志记录,写入磁盘后才返回,避免系统崩溃时丢失记录
+func (j *Journal) write(entry *JournalEntry) error {
+	entry.RunID = j.RunID
+	entry.Time = time.Now().Format(time.RFC3339)
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if _, err = j.file.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	return j.file.Sync()
+}
+
+// Close 关闭日志文件
+func (j *Journal) Close() error {
+	return j.file.Close()
+}
+
+// ReadJournal 读取指定运行ID的全部日志记录,按写入顺序返回,待完成记录已核对合并
+func ReadJournal(path, runID string) ([]*JournalEntry, error) {
+	entries, err := readJournal(path, func(entry *JournalEntry) bool {
+		return entry.RunID == runID
+	})
+	if err != nil {
+		return nil, err
+	}
+	return reconcilePending(entries), nil
+}
+
+// FindInterruptedRun 查找最近一次已开始但未正常结束的运行ID
+func FindInterruptedRun(path string) (string, error) {
+	var runIDs []string
+	finished := make(map[string]bool)
+	if _, err := readJournal(path, func(entry *JournalEntry) bool {
+		switch entry.Op {
+		case JournalOpStart:
+			runIDs = append(runIDs, entry.RunID)
+		case JournalOpFinish:
+			finished[entry.RunID] = true
+		}
+		return false
+	}); err != nil {
+		return "", err
+	}
+	for i := len(runIDs) - 1; i >= 0; i-- {
+		if !finished[runIDs[i]] {
+			return runIDs[i], nil
+		}
+	}
+	return "", fmt.Errorf("没有找到中断的运行记录")
+}
+
+// readJournal 按写入顺序读取满足条件的日志记录
+func readJournal(path string, match func(entry *JournalEntry) bool) ([]*JournalEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var entries []*JournalEntry
+	// 删除记录中带有文件内容,单行长度没有上限,不使用bufio.Scanner
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+		entry := &JournalEntry{}
+		// 跳过空行及进程中断时写入不完整的记录
+		if json.Unmarshal(line, entry) == nil && match(entry) {
+			entries = append(entries, entry)
+		}
+		if errors.Is(err, io.EOF) {
+			return entries, nil
+		}
+	}
+}
+
+// reconcilePending 合并同一次运行中的待完成记录:有完整记录时去掉待完成记录,删除记录的文件内容并入完整记录;
+// 没有完整记录说明进程在操作完成前后崩溃,原文件已不存在时按磁盘上的文件补全记录,否则操作未执行,直接去掉
+func reconcilePending(entries []*JournalEntry) []*JournalEntry {
+	pending := make(map[string]int) // 操作类型及原路径与待完成记录位置的映射
+	result := make([]*JournalEntry, 0, len(entries))
+	for _, entry := range entries {
+		key := entry.Op + "\x00" + entry.OldPath
+		if entry.Pending {
+			pending[key] = len(result)
+			result = append(result, entry)
+			continue
+		}
+		if i, ok := pending[key]; ok && entry.IsFileOp() {
+			if entry.Data == nil {
+				entry.Data, entry.Hash = result[i].Data, result[i].Hash
+			}
+			result[i] = nil
+			delete(pending, key)
+		}
+		result = append(result, entry)
+	}
+	for _, i := range pending {
+		result[i] = completePending(result[i])
+	}
+	reconciled := result[:0]
+	for _, entry := range result {
+		if entry != nil {
+			reconciled = append(reconciled, entry)
+		}
+	}
+	return reconciled
+}
+
+// completePending 核对没有完整记录的待完成记录,操作已完成时返回补全的记录,否则返回nil
+// 只复制的文件原文件仍在,无法确认目标文件是否为本次复制出的,不补全记录
+func completePending(entry *JournalEntry) *JournalEntry {
+	if entry.Op == JournalOpCopy {
+		return nil
+	}
+	if _, err := os.Stat(entry.OldPath); !os.IsNotExist(err) {
+		return nil
+	}
+	completed := *entry
+	completed.Pending = false
+	if entry.Op == JournalOpDelete {
+		return &completed
+	}
+	if entry.Op == JournalOpMove {
+		// 目标已存在时移动到第一个可用的_N后缀路径,即序列中最后一个存在的文件
+		completed.NewPath = ""
+		ResolveConflictPath(entry.NewPath, func(path string) bool {
+			_, err := os.Stat(path)
+			if err == nil {
+				completed.NewPath = path
+			}
+			return err == nil
+		})
+	}
+	if completed.NewPath == "" {
+		return nil
+	}
+	if matched, err := completed.MatchesFile(context.Background(), completed.NewPath); err != nil || !matched {
+		return nil
+	}
+	return &completed
+}
@@ -0,0 +1,153 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReconcilePending(t *testing.T) {
+	tests := []struct {
+		name string
+		op   string
+		// apply 写入待完成记录后模拟执行操作,返回之后写入的完整记录
+		apply    func(t *testing.T, journal *Journal, oldPath, newPath string) []*JournalEntry
+		wantNew  string // 核对后记录的目标文件名,为空时不应有文件操作记录
+		wantData bool   // 删除记录是否带有文件内容
+	}{
+		{"操作完成", JournalOpMove, func(t *testing.T, journal *Journal, oldPath, newPath string) []*JournalEntry {
+			mustRename(t, oldPath, newPath)
+			return []*JournalEntry{{Op: JournalOpMove, OldPath: oldPath, NewPath: newPath}}
+		}, "new.jpg", false},
+		{"移动后写入日志前崩溃", JournalOpMove, func(t *testing.T, journal *Journal, oldPath, newPath string) []*JournalEntry {
+			mustRename(t, oldPath, newPath)
+			return nil
+		}, "new.jpg", false},
+		{"移动到带后缀的路径后崩溃", JournalOpMove, func(t *testing.T, journal *Journal, oldPath, newPath string) []*JournalEntry {
+			if err := os.WriteFile(newPath, []byte("other photo"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			mustRename(t, oldPath, filepath.Join(filepath.Dir(newPath), "new_1.jpg"))
+			return nil
+		}, "new_1.jpg", false},
+		{"移动前崩溃", JournalOpMove, func(t *testing.T, journal *Journal, oldPath, newPath string) []*JournalEntry {
+			return nil
+		}, "", false},
+		{"目标文件不一致", JournalOpMove, func(t *testing.T, journal *Journal, oldPath, newPath string) []*JournalEntry {
+			if err := os.Remove(oldPath); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(newPath, []byte("other photo"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			return nil
+		}, "", false},
+		{"复制后崩溃", JournalOpCopy, func(t *testing.T, journal *Journal, oldPath, newPath string) []*JournalEntry {
+			if err := copyFile(oldPath, newPath); err != nil {
+				t.Fatal(err)
+			}
+			return nil
+		}, "", false},
+		{"删除后合并文件内容", JournalOpDelete, func(t *testing.T, journal *Journal, oldPath, newPath string) []*JournalEntry {
+			if err := os.Remove(oldPath); err != nil {
+				t.Fatal(err)
+			}
+			return []*JournalEntry{{Op: JournalOpDelete, OldPath: oldPath}}
+		}, "", true},
+		{"删除后写入日志前崩溃", JournalOpDelete, func(t *testing.T, journal *Journal, oldPath, newPath string) []*JournalEntry {
+			if err := os.Remove(oldPath); err != nil {
+				t.Fatal(err)
+			}
+			return nil
+		}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			oldPath, newPath := filepath.Join(dir, "photo.jpg"), filepath.Join(dir, "new.jpg")
+			if err := os.WriteFile(oldPath, []byte("photo"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			journalPath := filepath.Join(dir, JournalFileName)
+			journal, err := OpenJournal(journalPath, NewRunID())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer journal.Close()
+			if tt.op == JournalOpDelete {
+				err = journal.PendingDelete(oldPath, "", []byte("photo"))
+			} else {
+				err = journal.Pending(tt.op, oldPath, newPath)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, entry := range tt.apply(t, journal, oldPath, newPath) {
+				if err = journal.write(entry); err != nil {
+					t.Fatal(err)
+				}
+			}
+			entries, err := ReadJournal(journalPath, journal.RunID)
+			if err != nil {
+				t.Fatalf("ReadJournal() error = %v", err)
+			}
+			var ops []*JournalEntry
+			for _, entry := range entries {
+				if entry.Pending {
+					t.Errorf("pending entry %+v left after reconcile", entry)
+				}
+				if entry.IsFileOp() {
+					ops = append(ops, entry)
+				}
+			}
+			switch {
+			case tt.op == JournalOpDelete:
+				if len(ops) != 1 || (string(ops[0].Data) == "photo") != tt.wantData {
+					t.Errorf("entries = %+v, want one delete with data %v", ops, tt.wantData)
+				}
+			case tt.wantNew == "":
+				if len(ops) != 0 {
+					t.Errorf("entries = %+v, want none", ops)
+				}
+			case len(ops) != 1 || ops[0].NewPath != filepath.Join(dir, tt.wantNew):
+				t.Errorf("entries = %+v, want one entry to %s", ops, tt.wantNew)
+			}
+		})
+	}
+}
+
+// mustRename 重命名文件,失败时结束测试
+func mustRename(t *testing.T, oldPath, newPath string) {
+	t.Helper()
+	if err := os.Rename(oldPath, newPath); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReadJournalLongLine(t *testing.T) {
+	journalPath := filepath.Join(t.TempDir(), JournalFileName)
+	journal, err := OpenJournal(journalPath, NewRunID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 删除记录中的文件内容超过bufio.Scanner的单行上限
+	data := make([]byte, 2*1024*1024)
+	if err = journal.PendingDelete("/photos/photo.jpg.json", "", data); err != nil {
+		t.Fatal(err)
+	}
+	if err = journal.Append(JournalOpDelete, "/photos/photo.jpg.json", ""); err != nil {
+		t.Fatal(err)
+	}
+	// 进程中断时写入不完整的最后一行
+	if _, err = journal.file.WriteString(`{"run_id":"` + journal.RunID + `","op":"mo`); err != nil {
+		t.Fatal(err)
+	}
+	journal.Close()
+	entries, err := ReadJournal(journalPath, journal.RunID)
+	if err != nil {
+		t.Fatalf("ReadJournal() error = %v", err)
+	}
+	if len(entries) != 1 || len(entries[0].Data) != len(data) {
+		t.Errorf("ReadJournal() = %d entries, want one delete with %d bytes", len(entries), len(data))
+	}
+}
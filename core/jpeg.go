@@ -0,0 +1,62 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+
+	"github.com/thoas/go-funk"
+)
+
+// jpegXMPNamespace APP1中XMP数据的标识
+var jpegXMPNamespace = []byte("http://ns.adobe.com/xap/1.0/\x00")
+
+// errNotJPEG 文件不是JPEG格式
+var errNotJPEG = errors.New("不是有效的JPEG文件")
+
+// IsJPEG 判断文件是否为JPEG图片
+func IsJPEG(path string) bool {
+	return funk.ContainsString([]string{".JPG", ".JPEG", ".JFIF"}, GetExt(path))
+}
+
+// readJPEGXMP 读取JPEG APP1段中的XMP,读到图像数据(SOS)时停止,没有时返回nil
+func readJPEGXMP(filename string) ([]byte, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	reader := bufio.NewReader(file)
+	header := make([]byte, 4)
+	if _, err = io.ReadFull(reader, header[:2]); err != nil || header[0] != 0xff || header[1] != 0xd8 {
+		return nil, errNotJPEG
+	}
+	for {
+		// 段: 0xFF + 标记(1) + 长度(2,含自身) + 数据
+		if _, err = io.ReadFull(reader, header); err != nil || header[0] != 0xff {
+			return nil, nil
+		}
+		marker := header[1]
+		length := int(binary.BigEndian.Uint16(header[2:4])) - 2
+		// SOS之后为图像数据,EOI为文件结束
+		if marker == 0xda || marker == 0xd9 || length < 0 {
+			return nil, nil
+		}
+		if marker != 0xe1 || length < len(jpegXMPNamespace) {
+			if _, err = reader.Discard(length); err != nil {
+				return nil, nil
+			}
+			continue
+		}
+		data := make([]byte, length)
+		if _, err = io.ReadFull(reader, data); err != nil {
+			return nil, nil
+		}
+		if packet, ok := bytes.CutPrefix(data, jpegXMPNamespace); ok {
+			return packet, nil
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package core
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"math/bits"
+	"os"
+	"time"
+
+	"github.com/thoas/go-funk"
+)
+
+// Matroska中用到的EBML元素ID
+const (
+	ebmlIDHeader  = 0x1A45DFA3 // EBML头
+	ebmlIDSegment = 0x18538067 // Segment
+	ebmlIDInfo    = 0x1549A966 // Segment Info
+	ebmlIDCluster = 0x1F43B675 // Cluster,Info之后的媒体数据
+	ebmlIDDateUTC = 0x4461     // Segment Info中的DateUTC
+	ebmlIDScale   = 0x2AD7B1   // Segment Info中的TimestampScale,时长的单位(纳秒)
+	ebmlIDLength  = 0x4489     // Segment Info中的Duration
+)
+
+// matroskaDefaultScale 未设置TimestampScale时的默认值,即1毫秒
+const matroskaDefaultScale = 1000000
+
+// ebmlUnknownSize 长度未知的元素,延续到父元素末尾
+const ebmlUnknownSize = -1
+
+// matroskaEpoch DateUTC为自2001-01-01 UTC起的纳秒数
+var matroskaEpoch = time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// errNotMatroska 文件不是Matroska格式
+var errNotMatroska = errors.New("不是有效的MKV/WEBM文件")
+
+// IsMatroska 判断文件是否为Matroska(MKV/WEBM)格式的视频
+func IsMatroska(path string) bool {
+	return funk.ContainsString([]string{".MKV", ".WEBM"}, GetExt(path))
+}
+
+// ebmlReader 顺序读取EBML元素,记录当前位置以便跳过元素内容
+type ebmlReader struct {
+	file   *os.File
+	reader *bufio.Reader
+	offset int64
+}
+
+// GetMatroskaMetadata 读取MKV/WEBM文件Segment Info中的DateUTC及时长,时间为UTC,没有DateUTC时拍摄时间为零值
+func GetMatroskaMetadata(filename string) (*Metadata, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	r := &ebmlReader{file: file, reader: bufio.NewReader(file)}
+	id, size, err := r.readElementHeader()
+	if err != nil || id != ebmlIDHeader || size == ebmlUnknownSize {
+		return nil, errNotMatroska
+	}
+	if err = r.skip(size); err != nil {
+		return nil, errNotMatroska
+	}
+	for {
+		id, size, err = r.readElementHeader()
+		if errors.Is(err, io.EOF) {
+			return &Metadata{}, nil
+		} else if err != nil {
+			return nil, err
+		}
+		if id == ebmlIDSegment {
+			return r.findSegmentInfo(size)
+		}
+		if size == ebmlUnknownSize {
+			return &Metadata{}, nil
+		}
+		if err = r.skip(size); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// findSegmentInfo 在Segment中查找并读取Info,遇到Cluster时停止
+func (r *ebmlReader) findSegmentInfo(segmentSize int64) (*Metadata, error) {
+	end := r.offset + segmentSize
+	for segmentSize == ebmlUnknownSize || r.offset < end {
+		id, size, err := r.readElementHeader()
+		if errors.Is(err, io.EOF) {
+			return &Metadata{}, nil
+		} else if err != nil {
+			return nil, err
+		}
+		switch {
+		case id == ebmlIDInfo && size != ebmlUnknownSize:
+			return r.readInfo(size)
+		case id == ebmlIDCluster || size == ebmlUnknownSize:
+			// Info位于Cluster之前,无需读取媒体数据
+			return &Metadata{}, nil
+		}
+		if err = r.skip(size); err != nil {
+			return nil, err
+		}
+	}
+	return &Metadata{}, nil
+}
+
+// readInfo 读取Segment Info中的DateUTC及时长
+func (r *ebmlReader) readInfo(infoSize int64) (*Metadata, error) {
+	metadata := &Metadata{}
+	scale := uint64(matroskaDefaultScale)
+	var duration float64
+	end := r.offset + infoSize
+	for r.offset < end {
+		id, size, err := r.readElementHeader()
+		if err != nil {
+			return nil, err
+		}
+		if size == ebmlUnknownSize {
+			return nil, errNotMatroska
+		}
+		// SegmentUUID、MuxingApp、Title等其他元素长度不固定,直接跳过
+		if id != ebmlIDDateUTC && id != ebmlIDScale && id != ebmlIDLength {
+			if err = r.skip(size); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if size > 8 {
+			return nil, errNotMatroska
+		}
+		data := make([]byte, size)
+		if err = r.read(data); err != nil {
+			return nil, err
+		}
+		switch id {
+		case ebmlIDDateUTC:
+			if size != 8 {
+				return nil, errNotMatroska
+			}
+			nanoseconds := int64(binary.BigEndian.Uint64(data))
+			metadata.CaptureTime, metadata.OffsetKnown = matroskaEpoch.Add(time.Duration(nanoseconds)), true
+			// 与mediainfo一致,DateUTC为Encoded_Date
+			metadata.setDate(DateSourceVideoEncoded, metadata.CaptureTime, true)
+		case ebmlIDScale:
+			// 无符号整数,长度不固定
+			scale = 0
+			for _, b := range data {
+				scale = scale<<8 | uint64(b)
+			}
+		case ebmlIDLength:
+			switch size {
+			case 4:
+				duration = float64(math.Float32frombits(binary.BigEndian.Uint32(data)))
+			case 8:
+				duration = math.Float64frombits(binary.BigEndian.Uint64(data))
+			}
+		}
+	}
+	metadata.Duration = time.Duration(duration * float64(scale))
+	return metadata, nil
+}
+
+// readElementHeader 读取元素ID及内容长度
+func (r *ebmlReader) readElementHeader() (uint64, int64, error) {
+	id, _, err := r.readVint(true)
+	if err != nil {
+		return 0, 0, err
+	}
+	size, length, err := r.readVint(false)
+	if err != nil {
+		return 0, 0, err
+	}
+	// 数据位全为1表示长度未知
+	if size == 1<<(7*length)-1 {
+		return id, ebmlUnknownSize, nil
+	}
+	return id, int64(size), nil
+}
+
+// readVint 读取变长整数,keepMarker为true时保留长度标记位(元素ID)
+func (r *ebmlReader) readVint(keepMarker bool) (uint64, int, error) {
+	first, err := r.reader.ReadByte()
+	if err != nil {
+		return 0, 0, err
+	}
+	r.offset++
+	if first == 0 {
+		return 0, 0, errNotMatroska
+	}
+	length := bits.LeadingZeros8(first) + 1
+	value := uint64(first)
+	if !keepMarker {
+		value &= uint64(0xFF >> length)
+	}
+	for i := 1; i < length; i++ {
+		b, err := r.reader.ReadByte()
+		if err != nil {
+			return 0, 0, err
+		}
+		r.offset++
+		value = value<<8 | uint64(b)
+	}
+	return value, length, nil
+}
+
+// read 读取元素内容
+func (r *ebmlReader) read(data []byte) error {
+	n, err := io.ReadFull(r.reader, data)
+	r.offset += int64(n)
+	return err
+}
+
+// skip 跳过元素内容,较大的元素直接移动文件位置
+func (r *ebmlReader) skip(size int64) error {
+	if size <= int64(r.reader.Buffered()) {
+		_, err := r.reader.Discard(int(size))
+		r.offset += size
+		return err
+	}
+	r.offset += size
+	if _, err := r.file.Seek(r.offset, io.SeekStart); err != nil {
+		return err
+	}
+	r.reader.Reset(r.file)
+	return nil
+}
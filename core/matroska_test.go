@@ -0,0 +1,64 @@
+package core
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// ebmlElement 构造EBML元素,长度使用8字节变长整数
+func ebmlElement(id []byte, data []byte) []byte {
+	size := make([]byte, 8)
+	binary.BigEndian.PutUint64(size, uint64(len(data)))
+	size[0] = 0x01
+	return append(append(append([]byte{}, id...), size...), data...)
+}
+
+// buildMatroska 构造只包含EBML头及Segment Info的MKV文件
+func buildMatroska(infoChildren ...[]byte) []byte {
+	var info []byte
+	for _, child := range infoChildren {
+		info = append(info, child...)
+	}
+	header := ebmlElement([]byte{0x1A, 0x45, 0xDF, 0xA3}, ebmlElement([]byte{0x42, 0x82}, []byte("webm")))
+	segment := ebmlElement([]byte{0x18, 0x53, 0x80, 0x67}, ebmlElement([]byte{0x15, 0x49, 0xA9, 0x66}, info))
+	return append(header, segment...)
+}
+
+func TestGetMatroskaMetadata(t *testing.T) {
+	date := time.Date(2023, 5, 6, 7, 8, 9, 0, time.UTC)
+	dateUTC := make([]byte, 8)
+	binary.BigEndian.PutUint64(dateUTC, uint64(date.Sub(matroskaEpoch)))
+	dateElement := ebmlElement([]byte{0x44, 0x61}, dateUTC)
+	tests := []struct {
+		name     string
+		children [][]byte
+		want     time.Time
+	}{
+		{"只有DateUTC", [][]byte{dateElement}, date},
+		{"带有SegmentUUID及MuxingApp", [][]byte{
+			ebmlElement([]byte{0x73, 0xA4}, make([]byte, 16)),
+			ebmlElement([]byte{0x4D, 0x80}, []byte("Lavf60.3.100")),
+			ebmlElement([]byte{0x57, 0x41}, []byte("HandBrake 1.6.1 2023012300")),
+			dateElement,
+		}, date},
+		{"没有DateUTC", [][]byte{ebmlElement([]byte{0x7B, 0xA9}, []byte("title"))}, time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "video.mkv")
+			if err := os.WriteFile(path, buildMatroska(tt.children...), 0644); err != nil {
+				t.Fatal(err)
+			}
+			metadata, err := GetMatroskaMetadata(path)
+			if err != nil {
+				t.Fatalf("GetMatroskaMetadata() error = %v", err)
+			}
+			if !metadata.CaptureTime.Equal(tt.want) {
+				t.Errorf("CaptureTime = %v, want %v", metadata.CaptureTime, tt.want)
+			}
+		})
+	}
+}
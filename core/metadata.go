@@ -0,0 +1,249 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/thoas/go-funk"
+)
+
+// 元数据读取方式
+const (
+	MetadataBackendNative    = "native"    // 直接解析MP4/MOV/MKV/WEBM/MTS等格式及图片内嵌的XMP
+	MetadataBackendExif      = "exif"      // 读取图片的EXIF
+	MetadataBackendMediainfo = "mediainfo" // 使用mediainfo读取视频
+	MetadataBackendFFprobe   = "ffprobe"   // 使用ffprobe读取视频
+)
+
+// MetadataBackendTextMap 元数据读取方式说明
+var MetadataBackendTextMap = map[string]string{
+	MetadataBackendNative:    "直接解析MP4/MOV/MKV/WEBM/MTS等格式的视频,以及图片内嵌的XMP、PNG文本块等EXIF以外的时间,无需安装其他软件",
+	MetadataBackendExif:      "读取图片的EXIF",
+	MetadataBackendMediainfo: "使用mediainfo读取视频,需先安装mediainfo",
+	MetadataBackendFFprobe:   "使用ffprobe读取视频,需先安装ffmpeg",
+}
+
+// DefaultMetadataBackends 默认的元数据读取顺序,前面的读取到拍摄时间后不再使用后面的
+var DefaultMetadataBackends = []string{MetadataBackendNative, MetadataBackendExif, MetadataBackendMediainfo, MetadataBackendFFprobe}
+
+// metadataMaxChunkSize 图片中需要完整读取的元数据块(如PNG数据块、WebP的RIFF块、GIF应用扩展)的最大长度,防止异常文件占用过多内存
+const metadataMaxChunkSize = 4 << 20
+
+// ErrMetadataUnsupported 文件的实际格式不受该读取方式支持,将尝试下一种读取方式
+var ErrMetadataUnsupported = errors.New("不支持该文件格式")
+
+// ErrMetadataToolNotFound 未安装读取元数据所需的命令,将尝试下一种读取方式
+var ErrMetadataToolNotFound = errors.New("未找到命令")
+
+// Metadata 统一的文件元数据,未读取到的字段为零值
+type Metadata struct {
+	Provider    string                   // 读取到拍摄时间的读取方式
+	CaptureTime time.Time                // 拍摄时间,时区为拍摄时的时区
+	OffsetKnown bool                     // 拍摄时间是否带有时区信息,否则按本地时区保存拍摄时的当地时间
+	Make        string                   // 相机厂商
+	Model       string                   // 相机型号
+	GPS         *GPS                     // 拍摄地点,没有时为nil
+	Duration    time.Duration            // 视频时长
+	Width       int                      // 宽度
+	Height      int                      // 高度
+	Dates       map[string]*MetadataDate // 各个时间标签,键为日期来源名称,如exif:DateTimeOriginal
+}
+
+// MetadataDate 元数据中的一个时间
+type MetadataDate struct {
+	Time        time.Time // 时间,时区为记录时的时区
+	OffsetKnown bool      // 是否带有时区信息
+	Provider    string    // 读取到该时间的读取方式
+}
+
+// setDate 记录时间标签,时间为零值时忽略
+func (m *Metadata) setDate(source string, t time.Time, offsetKnown bool) {
+	if t.IsZero() {
+		return
+	}
+	if m.Dates == nil {
+		m.Dates = make(map[string]*MetadataDate)
+	}
+	m.Dates[source] = &MetadataDate{Time: t, OffsetKnown: offsetKnown}
+}
+
+// GPS 拍摄地点的经纬度
+type GPS struct {
+	Latitude  float64
+	Longitude float64
+}
+
+// HasCaptureTime 是否读取到拍摄时间
+func (m *Metadata) HasCaptureTime() bool {
+	return !m.CaptureTime.IsZero()
+}
+
+// merge 用另一份元数据补全未读取到的字段
+func (m *Metadata) merge(other *Metadata) {
+	if !m.HasCaptureTime() && other.HasCaptureTime() {
+		m.Provider, m.CaptureTime, m.OffsetKnown = other.Provider, other.CaptureTime, other.OffsetKnown
+	}
+	if m.Make == "" && m.Model == "" {
+		m.Make, m.Model = other.Make, other.Model
+	}
+	if m.GPS == nil {
+		m.GPS = other.GPS
+	}
+	if m.Duration == 0 {
+		m.Duration = other.Duration
+	}
+	if m.Width == 0 && m.Height == 0 {
+		m.Width, m.Height = other.Width, other.Height
+	}
+	for source, date := range other.Dates {
+		if m.Dates[source] == nil {
+			m.setDate(source, date.Time, date.OffsetKnown)
+			m.Dates[source].Provider = date.Provider
+		}
+	}
+}
+
+// MetadataProvider 元数据读取方式
+type MetadataProvider interface {
+	// Name 读取方式名称,用于命令行及配置文件
+	Name() string
+	// Supports 根据扩展名判断是否处理该文件
+	Supports(path string) bool
+	// Read 读取元数据,文件实际格式不受支持时返回ErrMetadataUnsupported,缺少命令时返回ErrMetadataToolNotFound
+	Read(ctx context.Context, path string) (*Metadata, error)
+}
+
+// metadataProviders 已注册的元数据读取方式
+var metadataProviders = map[string]MetadataProvider{
+	MetadataBackendNative:    &nativeMetadataProvider{},
+	MetadataBackendExif:      &exifMetadataProvider{},
+	MetadataBackendMediainfo: &mediainfoMetadataProvider{},
+	MetadataBackendFFprobe:   &ffprobeMetadataProvider{},
+}
+
+// RegisterMetadataProvider 注册元数据读取方式,同名时替换已有的读取方式,注册后可通过--metadata-backend或配置文件按名称使用
+func RegisterMetadataProvider(provider MetadataProvider) {
+	metadataProviders[provider.Name()] = provider
+}
+
+// MetadataBackends 返回排序后的全部元数据读取方式
+func MetadataBackends() []string {
+	backends := funk.Keys(metadataProviders).([]string)
+	sort.Strings(backends)
+	return backends
+}
+
+// ValidateMetadataBackends 校验元数据读取方式
+func ValidateMetadataBackends(names []string) error {
+	if len(names) == 0 {
+		return fmt.Errorf("至少需要指定一种元数据读取方式")
+	}
+	for _, name := range names {
+		if metadataProviders[name] == nil {
+			return fmt.Errorf("元数据读取方式%s不存在,可用方式:%s", name, strings.Join(MetadataBackends(), ","))
+		}
+	}
+	return nil
+}
+
+// MetadataReader 元数据读取器,按顺序使用各读取方式,直到读取到拍摄时间
+type MetadataReader struct {
+	Providers []MetadataProvider
+}
+
+func NewMetadataReader(providers ...MetadataProvider) *MetadataReader {
+	return &MetadataReader{Providers: providers}
+}
+
+// NewMetadataReaderByNames 按名称创建元数据读取器
+func NewMetadataReaderByNames(names []string) (*MetadataReader, error) {
+	if err := ValidateMetadataBackends(names); err != nil {
+		return nil, err
+	}
+	providers := make([]MetadataProvider, 0, len(names))
+	for _, name := range names {
+		providers = append(providers, metadataProviders[name])
+	}
+	return NewMetadataReader(providers...), nil
+}
+
+// Read 读取文件元数据,没有拍摄时间时CaptureTime为零值
+// 读取失败时继续使用后续读取方式,均未读取到元数据时返回错误,因缺少命令无法读取时优先返回ErrMetadataToolNotFound
+func (r *MetadataReader) Read(ctx context.Context, path string) (*Metadata, error) {
+	var result *Metadata
+	var toolErr, readErr error
+	for _, provider := range r.Providers {
+		if !provider.Supports(path) {
+			continue
+		}
+		metadata, err := provider.Read(ctx, path)
+		switch {
+		case errors.Is(err, ErrMetadataUnsupported):
+			continue
+		case errors.Is(err, ErrMetadataToolNotFound):
+			// 保留首个缺少的命令,其他读取方式也无法读取时提示安装
+			if toolErr == nil {
+				toolErr = err
+			}
+			continue
+		case err != nil:
+			// 文件头损坏等错误只影响该读取方式,其他读取方式可能仍能读取
+			if readErr == nil {
+				readErr = fmt.Errorf("%s: %w", provider.Name(), err)
+			}
+			continue
+		}
+		metadata.Provider = provider.Name()
+		for _, date := range metadata.Dates {
+			date.Provider = provider.Name()
+		}
+		if result == nil {
+			result = metadata
+		} else {
+			result.merge(metadata)
+		}
+		if result.HasCaptureTime() {
+			return result, nil
+		}
+	}
+	if result == nil && toolErr != nil {
+		return nil, toolErr
+	}
+	if result == nil && readErr != nil {
+		return nil, readErr
+	}
+	if result == nil {
+		result = &Metadata{}
+	}
+	// 没有拍摄时间时不记录读取方式
+	if !result.HasCaptureTime() {
+		result.Provider = ""
+	}
+	return result, nil
+}
+
+// iso6709Pattern ISO 6709格式的位置,如+37.7749-122.4194+010.000/
+var iso6709Pattern = regexp.MustCompile(`^([+-]\d+(?:\.\d+)?)([+-]\d+(?:\.\d+)?)`)
+
+// parseISO6709 解析视频元数据中ISO 6709格式的拍摄地点,仅支持十进制度数
+func parseISO6709(value string) *GPS {
+	matches := iso6709Pattern.FindStringSubmatch(strings.TrimSpace(value))
+	if matches == nil {
+		return nil
+	}
+	latitude, err := strconv.ParseFloat(matches[1], 64)
+	if err != nil {
+		return nil
+	}
+	longitude, err := strconv.ParseFloat(matches[2], 64)
+	if err != nil {
+		return nil
+	}
+	return &GPS{Latitude: latitude, Longitude: longitude}
+}
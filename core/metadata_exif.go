@@ -0,0 +1,218 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dsoprea/go-exif/v3"
+	exifcommon "github.com/dsoprea/go-exif/v3/common"
+)
+
+// exifMetadataProvider 读取图片EXIF中的拍摄时间、相机、GPS及尺寸
+type exifMetadataProvider struct{}
+
+func (p *exifMetadataProvider) Name() string {
+	return MetadataBackendExif
+}
+
+func (p *exifMetadataProvider) Supports(path string) bool {
+	return IsImage(path)
+}
+
+// Read 读取EXIF,拍摄时间为DateTimeOriginal,带有OffsetTimeOriginal时使用该时区,无法解析的时间视为没有该时间
+func (p *exifMetadataProvider) Read(ctx context.Context, path string) (*Metadata, error) {
+	tags, err := readExifTags(path)
+	if err != nil {
+		return nil, err
+	}
+	metadata := &Metadata{
+		Make:  exifString(tags["Make"]),
+		Model: exifString(tags["Model"]),
+		GPS:   exifGPS(tags),
+	}
+	// 时间、亚秒及时区分别保存在不同标签中
+	for _, tag := range []string{"DateTimeOriginal", "DateTimeDigitized", "DateTime"} {
+		suffix := strings.TrimPrefix(tag, "DateTime")
+		location, offsetKnown := time.Local, false
+		if offset := parseExifOffset(exifString(tags["OffsetTime"+suffix])); offset != nil {
+			location, offsetKnown = offset, true
+		}
+		date, err := ParseDateTime(exifString(tags[tag]), location)
+		if err != nil || date == nil {
+			continue
+		}
+		metadata.setDate(exifDateSourcePrefix+tag, withExifSubSec(date.Time, exifString(tags["SubSecTime"+suffix])), offsetKnown || date.OffsetKnown)
+	}
+	if date := metadata.Dates[DateSourceExifOriginal]; date != nil {
+		metadata.CaptureTime, metadata.OffsetKnown = date.Time, date.OffsetKnown
+	}
+	// 优先使用Exif IFD中的实际尺寸,IFD0中的尺寸在部分相机中为缩略图尺寸
+	metadata.Width, metadata.Height = exifInt(tags["PixelXDimension"]), exifInt(tags["PixelYDimension"])
+	if metadata.Width == 0 || metadata.Height == 0 {
+		metadata.Width, metadata.Height = exifInt(tags["ImageWidth"]), exifInt(tags["ImageLength"])
+	}
+	return metadata, nil
+}
+
+// readExifTags 读取全部EXIF标签,同名标签保留首个(IFD0优先于缩略图IFD1),没有EXIF时返回空
+// CR3及TIFF结构的RAW直接读取标签,扩展名与实际格式不符时按普通文件搜索
+func readExifTags(path string) (map[string]interface{}, error) {
+	tags := make(map[string]interface{})
+	switch {
+	case IsCR3(path):
+		cr3Tags, err := GetCR3ExifTags(path)
+		if !errors.Is(err, errNotCR3) {
+			return cr3Tags, err
+		}
+	case IsTIFFRaw(path):
+		tiffTags, err := GetTIFFExifTags(path)
+		if err != nil && !errors.Is(err, errNotTIFF) {
+			return nil, err
+		}
+		// RW2等格式的拍摄时间保存在内嵌的JPEG预览中,IFD0中没有时继续搜索
+		if tiffTags["DateTimeOriginal"] != nil {
+			return tiffTags, nil
+		}
+		for name, value := range tiffTags {
+			tags[name] = value
+		}
+	}
+	dt, err := extractExif(path)
+	if errors.Is(err, exif.ErrNoExif) || (err == nil && dt == nil) {
+		return tags, nil
+	} else if err != nil {
+		return nil, err
+	}
+	ets, _, err := exif.GetFlatExifData(dt, &exif.ScanOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, et := range ets {
+		if _, ok := tags[et.TagName]; !ok {
+			tags[et.TagName] = et.Value
+		}
+	}
+	return tags, nil
+}
+
+// extractExif 提取以TIFF头开始的EXIF数据,HEIF、PNG及WebP通过文件结构直接定位,其他格式在文件中搜索EXIF头
+// 扩展名与实际格式不符时按普通文件搜索
+func extractExif(path string) ([]byte, error) {
+	switch {
+	case IsHEIF(path):
+		dt, err := GetHEIFExif(path)
+		if !errors.Is(err, errNotHEIF) {
+			return dt, err
+		}
+	case IsPNG(path):
+		png, err := readPNGMetadata(path)
+		if err == nil {
+			return png.exif, nil
+		} else if !errors.Is(err, errNotPNG) {
+			return nil, err
+		}
+	case IsWebP(path):
+		webp, err := readWebPMetadata(path)
+		if err == nil {
+			return webp.exif, nil
+		} else if !errors.Is(err, errNotWebP) {
+			return nil, err
+		}
+	}
+	return exif.SearchFileAndExtractExif(path)
+}
+
+// exifString 将EXIF标签值转换为字符串,去掉末尾的空格及\x00
+func exifString(value interface{}) string {
+	if value == nil {
+		return ""
+	}
+	return strings.Trim(fmt.Sprintf("%s", value), " \x00")
+}
+
+// exifInt 读取EXIF中的整数标签
+func exifInt(value interface{}) int {
+	switch v := value.(type) {
+	case []uint16:
+		if len(v) > 0 {
+			return int(v[0])
+		}
+	case []uint32:
+		if len(v) > 0 {
+			return int(v[0])
+		}
+	}
+	return 0
+}
+
+// exifGPS 读取EXIF中的GPS经纬度,度分秒换算为十进制度数
+func exifGPS(tags map[string]interface{}) *GPS {
+	latitude, ok := exifDegrees(tags["GPSLatitude"])
+	if !ok {
+		return nil
+	}
+	longitude, ok := exifDegrees(tags["GPSLongitude"])
+	if !ok {
+		return nil
+	}
+	if exifString(tags["GPSLatitudeRef"]) == "S" {
+		latitude = -latitude
+	}
+	if exifString(tags["GPSLongitudeRef"]) == "W" {
+		longitude = -longitude
+	}
+	return &GPS{Latitude: latitude, Longitude: longitude}
+}
+
+// exifDegrees 将度、分、秒三个有理数换算为度数
+func exifDegrees(value interface{}) (float64, bool) {
+	rationals, ok := value.([]exifcommon.Rational)
+	if !ok || len(rationals) != 3 {
+		return 0, false
+	}
+	var degrees float64
+	for i, unit := range []float64{1, 60, 3600} {
+		if rationals[i].Denominator == 0 {
+			return 0, false
+		}
+		degrees += float64(rationals[i].Numerator) / float64(rationals[i].Denominator) / unit
+	}
+	return degrees, true
+}
+
+// parseExifOffset 解析OffsetTime*标签中的时区偏移,如+08:00,无法解析时返回nil
+func parseExifOffset(offset string) *time.Location {
+	if offset == "" {
+		return nil
+	}
+	res, err := time.Parse("-07:00", offset)
+	if err != nil {
+		return nil
+	}
+	_, seconds := res.Zone()
+	return time.FixedZone(offset, seconds)
+}
+
+// withExifSubSec 为不含亚秒的时间加上SubSecTime*标签中的亚秒,精确到毫秒
+func withExifSubSec(t time.Time, subSec string) time.Time {
+	digits := exifSubSecDigits(subSec)
+	if digits == "" || t.Nanosecond() != 0 {
+		return t
+	}
+	ms, _ := strconv.Atoi((digits + "00")[:3])
+	return t.Add(time.Duration(ms) * time.Millisecond)
+}
+
+// exifSubSecDigits 提取亚秒中的数字,亚秒为秒的小数部分,如5表示500毫秒,123456表示123毫秒
+func exifSubSecDigits(subSec string) string {
+	return strings.Map(func(r rune) rune {
+		if r < '0' || r > '9' {
+			return -1
+		}
+		return r
+	}, subSec)
+}
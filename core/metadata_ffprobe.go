@@ -0,0 +1,84 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+// ffprobeExists ffprobe命令是否存在,只检查一次
+var ffprobeExists = sync.OnceValue(func() bool {
+	return CommandExists("ffprobe")
+})
+
+// CheckFFprobeCommandExists 检查ffprobe命令是否存在,不存在时返回包含下载链接的ErrMetadataToolNotFound
+func CheckFFprobeCommandExists() error {
+	if ffprobeExists() {
+		return nil
+	}
+	return fmt.Errorf("%w ffprobe,获取该格式视频的拍摄时间需要先安装ffmpeg\n下载链接:%s", ErrMetadataToolNotFound, "https://ffmpeg.org/download.html")
+}
+
+// ffprobeMetadataProvider 使用ffprobe读取视频元数据
+type ffprobeMetadataProvider struct{}
+
+func (p *ffprobeMetadataProvider) Name() string {
+	return MetadataBackendFFprobe
+}
+
+func (p *ffprobeMetadataProvider) Supports(path string) bool {
+	return IsVideo(path)
+}
+
+// Read 读取ffprobe的JSON输出,拍摄时间优先使用QuickTime元数据中的拍摄时间,其次为creation_time
+func (p *ffprobeMetadataProvider) Read(ctx context.Context, path string) (*Metadata, error) {
+	if err := CheckFFprobeCommandExists(); err != nil {
+		return nil, err
+	}
+	cmd := exec.CommandContext(ctx, "ffprobe", "-v", "quiet", "-print_format", "json", "-show_format", "-show_streams", path)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	if err := cmd.Run(); err != nil {
+		return nil, err
+	}
+	data := strings.TrimSpace(out.String())
+	if data == "" {
+		return &Metadata{}, nil
+	}
+	tags := gjson.Get(data, "format.tags")
+	video := gjson.Get(data, `streams.#(codec_type="video")`)
+	metadata := &Metadata{
+		Make:   tags.Get(`com\.apple\.quicktime\.make`).String(),
+		Model:  tags.Get(`com\.apple\.quicktime\.model`).String(),
+		Width:  int(video.Get("width").Int()),
+		Height: int(video.Get("height").Int()),
+	}
+	if seconds, err := strconv.ParseFloat(gjson.Get(data, "format.duration").String(), 64); err == nil {
+		metadata.Duration = time.Duration(seconds * float64(time.Second))
+	}
+	for _, location := range []string{tags.Get(`com\.apple\.quicktime\.location\.ISO6709`).String(), tags.Get("location").String()} {
+		if metadata.GPS = parseISO6709(location); metadata.GPS != nil {
+			break
+		}
+	}
+	// 创建时间为UTC,如2024-05-02T03:04:05.000000Z,与mediainfo的Encoded_Date一致
+	if date, err := ParseDateTime(tags.Get("creation_time").String(), time.UTC); err == nil && date != nil {
+		metadata.CaptureTime, metadata.OffsetKnown = date.Time, true
+		metadata.setDate(DateSourceVideoEncoded, date.Time, true)
+	}
+	// QuickTime拍摄时间带有拍摄地的时区,与mediainfo的Recorded_Date一致,优先使用
+	if creationDate := tags.Get(`com\.apple\.quicktime\.creationdate`).String(); creationDate != "" {
+		if res, offsetKnown := parseISOBMFFCreationDate(creationDate); !res.IsZero() {
+			metadata.CaptureTime, metadata.OffsetKnown = res, offsetKnown
+			metadata.setDate(DateSourceVideoRecorded, res, offsetKnown)
+		}
+	}
+	return metadata, nil
+}
@@ -0,0 +1,92 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+// mediainfoExists mediainfo命令是否存在,只检查一次
+var mediainfoExists = sync.OnceValue(func() bool {
+	return CommandExists("mediainfo")
+})
+
+// CheckMediainfoCommandExists 检查mediainfo命令是否存在,不存在时返回包含下载链接的ErrMetadataToolNotFound
+func CheckMediainfoCommandExists() error {
+	if mediainfoExists() {
+		return nil
+	}
+	link := "https://mediaarea.net/en/MediaInfo/Download"
+	if runtime.GOOS == "windows" {
+		link = "https://mediaarea.net/download/binary/mediainfo-gui/25.04/MediaInfo_GUI_25.04_Windows.exe"
+	}
+	return fmt.Errorf("%w mediainfo,获取该格式视频的拍摄时间需要先安装mediainfo\n下载链接:%s", ErrMetadataToolNotFound, link)
+}
+
+// mediainfoMetadataProvider 使用mediainfo读取视频元数据
+type mediainfoMetadataProvider struct{}
+
+func (p *mediainfoMetadataProvider) Name() string {
+	return MetadataBackendMediainfo
+}
+
+func (p *mediainfoMetadataProvider) Supports(path string) bool {
+	return IsVideo(path)
+}
+
+// Read 读取mediainfo的JSON输出,拍摄时间优先使用Recorded_Date,其次为Encoded_Date
+func (p *mediainfoMetadataProvider) Read(ctx context.Context, path string) (*Metadata, error) {
+	if err := CheckMediainfoCommandExists(); err != nil {
+		return nil, err
+	}
+	cmd := exec.CommandContext(ctx, "mediainfo", "--Output=JSON", path)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	if err := cmd.Run(); err != nil {
+		return nil, err
+	}
+	data := strings.TrimSpace(out.String())
+	if data == "" {
+		return &Metadata{}, nil
+	}
+	general := gjson.Get(data, `media.track.#(@type="General")`)
+	video := gjson.Get(data, `media.track.#(@type="Video")`)
+	metadata := &Metadata{
+		Make:   general.Get("extra.com_apple_quicktime_make").String(),
+		Model:  general.Get("extra.com_apple_quicktime_model").String(),
+		Width:  int(video.Get("Width").Int()),
+		Height: int(video.Get("Height").Int()),
+	}
+	if seconds, err := strconv.ParseFloat(general.Get("Duration").String(), 64); err == nil {
+		metadata.Duration = time.Duration(seconds * float64(time.Second))
+	}
+	for _, location := range []string{general.Get("extra.com_apple_quicktime_location_ISO6709").String(), general.Get("Recorded_Location").String()} {
+		if metadata.GPS = parseISO6709(location); metadata.GPS != nil {
+			break
+		}
+	}
+	// 标记时间及编码时间为UTC,拍摄时间带有拍摄地的时区,没有时区时为当地时间,无法解析时忽略
+	for source, field := range map[string]string{DateSourceVideoTagged: "Tagged_Date", DateSourceVideoEncoded: "Encoded_Date"} {
+		if date, err := ParseDateTime(general.Get(field).String(), time.UTC); err == nil && date != nil {
+			metadata.setDate(source, date.Time, true)
+		}
+	}
+	if date, err := ParseDateTime(general.Get("Recorded_Date").String(), time.Local); err == nil && date != nil {
+		metadata.setDate(DateSourceVideoRecorded, date.Time, date.OffsetKnown)
+	}
+	for _, source := range []string{DateSourceVideoRecorded, DateSourceVideoEncoded} {
+		if date := metadata.Dates[source]; date != nil {
+			metadata.CaptureTime, metadata.OffsetKnown = date.Time, date.OffsetKnown
+			break
+		}
+	}
+	return metadata, nil
+}
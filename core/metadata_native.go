@@ -0,0 +1,67 @@
+package core
+
+import (
+	"context"
+	"errors"
+)
+
+// nativeMetadataProvider 直接解析MP4/MOV、MKV/WEBM及MTS/M2TS视频,以及图片内嵌的XMP、PNG文本块等EXIF以外的时间,无需安装其他软件
+type nativeMetadataProvider struct{}
+
+func (p *nativeMetadataProvider) Name() string {
+	return MetadataBackendNative
+}
+
+func (p *nativeMetadataProvider) Supports(path string) bool {
+	return IsISOBMFF(path) || IsMatroska(path) || IsAVCHD(path) || HasEmbeddedXMP(path)
+}
+
+// Read 按扩展名选择解析方式,扩展名与实际格式不符时返回ErrMetadataUnsupported
+func (p *nativeMetadataProvider) Read(ctx context.Context, path string) (*Metadata, error) {
+	var metadata *Metadata
+	var err error
+	switch {
+	case IsISOBMFF(path):
+		metadata, err = GetISOBMFFMetadata(path)
+	case IsMatroska(path):
+		metadata, err = GetMatroskaMetadata(path)
+	case IsAVCHD(path):
+		// MDPM中的时间为拍摄地的当地时间,不带时区
+		metadata = &Metadata{}
+		metadata.CaptureTime, err = GetAVCHDDate(path)
+		metadata.setDate(DateSourceVideoRecorded, metadata.CaptureTime, false)
+	case IsPNG(path):
+		metadata, err = getPNGMetadata(path)
+	case HasEmbeddedXMP(path):
+		// 图片不设置拍摄时间,以便继续使用exif读取方式读取EXIF中的拍摄时间
+		var xmp []byte
+		if xmp, err = ReadEmbeddedXMP(path); err == nil {
+			metadata = xmpMetadata(xmp)
+		}
+	}
+	if errors.Is(err, errNotISOBMFF) || errors.Is(err, errNotMatroska) || errors.Is(err, errNotAVCHD) ||
+		errors.Is(err, errNotPNG) || errors.Is(err, errNotWebP) || errors.Is(err, errNotGIF) ||
+		errors.Is(err, errNotJPEG) || errors.Is(err, errNotHEIF) || errors.Is(err, errNotCR3) || errors.Is(err, errNotTIFF) {
+		return nil, ErrMetadataUnsupported
+	}
+	if err != nil {
+		return nil, err
+	}
+	return metadata, nil
+}
+
+// getPNGMetadata 读取PNG内嵌XMP及文本块中的时间
+// 图片不设置拍摄时间,以便继续使用exif读取方式读取eXIf中的拍摄时间
+func getPNGMetadata(path string) (*Metadata, error) {
+	png, err := readPNGMetadata(path)
+	if err != nil {
+		return nil, err
+	}
+	metadata := xmpMetadata(png.xmp)
+	for source, date := range map[string]*MetadataDate{DateSourcePNGCreated: png.creationTime, DateSourcePNGModified: png.modifyTime} {
+		if date != nil {
+			metadata.setDate(source, date.Time, date.OffsetKnown)
+		}
+	}
+	return metadata, nil
+}
@@ -0,0 +1,179 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// fakeProvider 测试用的元数据读取方式,返回固定的元数据或错误,并记录是否被调用
+type fakeProvider struct {
+	name        string
+	unsupported bool // Supports返回false
+	metadata    *Metadata
+	err         error
+	called      bool
+}
+
+func (p *fakeProvider) Name() string {
+	return p.name
+}
+
+func (p *fakeProvider) Supports(path string) bool {
+	return !p.unsupported
+}
+
+func (p *fakeProvider) Read(ctx context.Context, path string) (*Metadata, error) {
+	p.called = true
+	if p.err != nil {
+		return nil, p.err
+	}
+	// 每次返回副本,避免读取器修改测试数据
+	metadata := *p.metadata
+	return &metadata, nil
+}
+
+func TestMetadataReaderRead(t *testing.T) {
+	captureTime := time.Date(2024, 5, 2, 3, 4, 5, 0, time.UTC)
+	errCorrupt := errors.New("文件头损坏")
+	tests := []struct {
+		name         string
+		providers    []*fakeProvider
+		wantProvider string
+		wantTime     time.Time
+		wantMake     string
+		wantCalled   []bool
+		wantErr      error
+	}{
+		{
+			name: "按顺序使用首个读取到拍摄时间的读取方式",
+			providers: []*fakeProvider{
+				{name: "first", metadata: &Metadata{CaptureTime: captureTime, Make: "Canon"}},
+				{name: "second", metadata: &Metadata{CaptureTime: captureTime.Add(time.Hour)}},
+			},
+			wantProvider: "first",
+			wantTime:     captureTime,
+			wantMake:     "Canon",
+			wantCalled:   []bool{true, false},
+		},
+		{
+			name: "读取失败时使用下一种读取方式",
+			providers: []*fakeProvider{
+				{name: "first", err: errCorrupt},
+				{name: "second", metadata: &Metadata{CaptureTime: captureTime}},
+			},
+			wantProvider: "second",
+			wantTime:     captureTime,
+			wantCalled:   []bool{true, true},
+		},
+		{
+			name: "没有拍摄时间时使用下一种读取方式并保留已读取的字段",
+			providers: []*fakeProvider{
+				{name: "first", metadata: &Metadata{Make: "Apple"}},
+				{name: "second", metadata: &Metadata{CaptureTime: captureTime, Make: "Sony"}},
+			},
+			wantProvider: "second",
+			wantTime:     captureTime,
+			wantMake:     "Apple",
+			wantCalled:   []bool{true, true},
+		},
+		{
+			name: "跳过不支持的文件格式",
+			providers: []*fakeProvider{
+				{name: "first", unsupported: true, metadata: &Metadata{CaptureTime: captureTime.Add(time.Hour)}},
+				{name: "second", err: ErrMetadataUnsupported},
+				{name: "third", metadata: &Metadata{CaptureTime: captureTime}},
+			},
+			wantProvider: "third",
+			wantTime:     captureTime,
+			wantCalled:   []bool{false, true, true},
+		},
+		{
+			name: "均没有拍摄时间时返回空的读取方式",
+			providers: []*fakeProvider{
+				{name: "first", metadata: &Metadata{Make: "Apple"}},
+				{name: "second", err: errCorrupt},
+			},
+			wantMake:   "Apple",
+			wantCalled: []bool{true, true},
+		},
+		{
+			name: "均读取失败时返回错误",
+			providers: []*fakeProvider{
+				{name: "first", err: errCorrupt},
+				{name: "second", err: ErrMetadataUnsupported},
+			},
+			wantCalled: []bool{true, true},
+			wantErr:    errCorrupt,
+		},
+		{
+			name: "缺少命令时优先提示安装",
+			providers: []*fakeProvider{
+				{name: "first", err: errCorrupt},
+				{name: "second", err: ErrMetadataToolNotFound},
+			},
+			wantCalled: []bool{true, true},
+			wantErr:    ErrMetadataToolNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			providers := make([]MetadataProvider, 0, len(tt.providers))
+			for _, provider := range tt.providers {
+				providers = append(providers, provider)
+			}
+			metadata, err := NewMetadataReader(providers...).Read(context.Background(), "photo.jpg")
+			for i, provider := range tt.providers {
+				if provider.called != tt.wantCalled[i] {
+					t.Errorf("%s called = %v, want %v", provider.name, provider.called, tt.wantCalled[i])
+				}
+			}
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Read() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Read() error = %v", err)
+			}
+			if metadata.Provider != tt.wantProvider {
+				t.Errorf("Provider = %q, want %q", metadata.Provider, tt.wantProvider)
+			}
+			if !metadata.CaptureTime.Equal(tt.wantTime) {
+				t.Errorf("CaptureTime = %v, want %v", metadata.CaptureTime, tt.wantTime)
+			}
+			if metadata.Make != tt.wantMake {
+				t.Errorf("Make = %q, want %q", metadata.Make, tt.wantMake)
+			}
+		})
+	}
+}
+
+func TestNewRenamerMetadataProviders(t *testing.T) {
+	provider := &fakeProvider{name: "fake", metadata: &Metadata{}}
+	renamer, err := NewRenamer(&Options{MetadataProviders: []MetadataProvider{provider}}, NewPlanOperator())
+	if err != nil {
+		t.Fatalf("NewRenamer() error = %v", err)
+	}
+	if want := []MetadataProvider{provider}; !reflect.DeepEqual(renamer.Metadata.Providers, want) {
+		t.Errorf("Providers = %v, want %v", renamer.Metadata.Providers, want)
+	}
+}
+
+func TestRegisterMetadataProvider(t *testing.T) {
+	provider := &fakeProvider{name: "fake", metadata: &Metadata{}}
+	RegisterMetadataProvider(provider)
+	t.Cleanup(func() {
+		delete(metadataProviders, provider.Name())
+	})
+	reader, err := NewMetadataReaderByNames([]string{"fake", MetadataBackendExif})
+	if err != nil {
+		t.Fatalf("NewMetadataReaderByNames() error = %v", err)
+	}
+	if len(reader.Providers) != 2 || reader.Providers[0] != MetadataProvider(provider) || reader.Providers[1].Name() != MetadataBackendExif {
+		t.Errorf("Providers = %v, want [fake exif]", reader.Providers)
+	}
+}
@@ -0,0 +1,72 @@
+package core
+
+import (
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Namer 文件命名器,根据文件名模板及前缀规则生成新文件名
+type Namer struct {
+	Template    *Template
+	PrefixRules []*PrefixRule   // 前缀规则,已包含默认规则
+	ExtPolicy   *ExtPolicy      // 扩展名规范化策略
+	Target      string          // 整理模式的目标目录
+	Folder      *FolderTemplate // 整理模式的文件夹模板,为空时在原文件夹中重命名
+	counter     int
+}
+
+func NewNamer(template *Template, prefixRules []*PrefixRule, extPolicy *ExtPolicy) *Namer {
+	return &Namer{
+		Template:    template,
+		PrefixRules: append(append([]*PrefixRule{}, prefixRules...), DefaultPrefixRules...),
+		ExtPolicy:   extPolicy,
+	}
+}
+
+// Organize 设置整理模式,文件将移至目标目录中按拍摄时间分层的文件夹
+func (n *Namer) Organize(target string, folder *FolderTemplate) {
+	n.Target = target
+	n.Folder = folder
+}
+
+// GetDateFilePath 获取带日期的文件路径(含后缀名),整理模式下位于目标目录中按日期分层的文件夹内
+func (n *Namer) GetDateFilePath(dateTime time.Time, path string) string {
+	n.counter++
+	return n.render(dateTime, newMediaFile(path), n.counter, filepath.Dir(path))
+}
+
+// GetUnknownDatePath 获取没有拍摄日期的文件在unknown-date文件夹中的路径,不修改文件名
+func (n *Namer) GetUnknownDatePath(path string) string {
+	dir := filepath.Dir(path)
+	if n.Folder != nil {
+		dir = n.Target
+	}
+	return filepath.Join(dir, UnknownDateDir, filepath.Base(path))
+}
+
+// render 按模板渲染文件路径,非整理模式下文件位于dir中
+func (n *Namer) render(dateTime time.Time, file *mediaFile, counter int, dir string) string {
+	data := n.templateData(dateTime, file, counter)
+	if n.Folder != nil {
+		dir = filepath.Join(n.Target, n.Folder.Render(data))
+	}
+	return filepath.Join(dir, n.Template.Render(data))
+}
+
+// templateData 生成模板渲染数据
+func (n *Namer) templateData(dateTime time.Time, file *mediaFile, counter int) *TemplateData {
+	fileName := filepath.Base(file.path)
+	data := &TemplateData{
+		Time:    dateTime,
+		Prefix:  MatchPrefix(n.PrefixRules, file),
+		Name:    strings.TrimSuffix(fileName, filepath.Ext(fileName)),
+		Ext:     strings.TrimPrefix(n.ExtPolicy.Normalize(filepath.Ext(fileName)), "."),
+		Counter: counter,
+	}
+	if n.Template.UsesCamera() {
+		// 相机信息只作为文件名的一部分,读取失败时留空
+		data.Make, data.Model = file.camera()
+	}
+	return data
+}
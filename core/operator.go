@@ -0,0 +1,254 @@
+package core
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+)
+
+// FileOperator 文件操作器,负责执行或模拟文件的移动
+type FileOperator interface {
+	// Move 移动文件,目标文件已存在时自动添加_N后缀,返回最终路径
+	Move(oldPath, newPath string) (string, error)
+	// Replace 移动文件,目标文件已存在时直接覆盖
+	Replace(oldPath, newPath string) error
+	// Copy 复制文件并校验md5,目标文件已存在时自动添加_N后缀,返回最终路径;removeSource为true时校验通过后删除原文件
+	Copy(oldPath, newPath string, removeSource bool) (string, error)
+	// Remove 删除文件,用于删除随原文件处理的附属文件
+	Remove(path string) error
+	// Exists 判断路径是否已存在
+	Exists(path string) bool
+}
+
+// DiskOperator 直接操作磁盘文件
+type DiskOperator struct {
+}
+
+func NewDiskOperator() *DiskOperator {
+	return &DiskOperator{}
+}
+
+// Move 移动文件,目标目录不存在时自动创建
+func (o *DiskOperator) Move(oldPath, newPath string) (string, error) {
+	if err := os.MkdirAll(filepath.Dir(newPath), 0755); err != nil {
+		return newPath, err
+	}
+	return RenameWithConflictResolution(oldPath, newPath)
+}
+
+// Replace 移动文件并覆盖目标文件
+func (o *DiskOperator) Replace(oldPath, newPath string) error {
+	return MoveFile(oldPath, newPath)
+}
+
+// Copy 复制文件,目标目录不存在时自动创建,校验通过前不会修改原文件
+func (o *DiskOperator) Copy(oldPath, newPath string, removeSource bool) (string, error) {
+	if oldPath == newPath {
+		return oldPath, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(newPath), 0755); err != nil {
+		return newPath, err
+	}
+	newPath, err := CopyWithConflictResolution(oldPath, newPath)
+	if err != nil || !removeSource {
+		return newPath, err
+	}
+	return newPath, os.Remove(oldPath)
+}
+
+// Remove 删除文件
+func (o *DiskOperator) Remove(path string) error {
+	return os.Remove(path)
+}
+
+// Exists 判断路径是否已存在
+func (o *DiskOperator) Exists(path string) bool {
+	_, err := os.Stat(path)
+	return !os.IsNotExist(err)
+}
+
+// PlanItem 重命名计划项
+type PlanItem struct {
+	OldPath string // 原路径
+	NewPath string // 目标路径
+	Replace bool   // 是否覆盖目标文件
+	Copy    bool   // 是否复制文件,原文件保留
+	Delete  bool   // 是否删除文件,此时没有目标路径
+}
+
+// PlanOperator 只生成重命名计划,不修改磁盘
+type PlanOperator struct {
+	Items    []*PlanItem     // 重命名计划
+	occupied map[string]bool // 按计划执行后会被占用的路径
+	vacated  map[string]bool // 按计划执行后会被移走的路径
+}
+
+func NewPlanOperator() *PlanOperator {
+	return &PlanOperator{
+		occupied: make(map[string]bool),
+		vacated:  make(map[string]bool),
+	}
+}
+
+// Move 模拟移动文件,与RenameWithConflictResolution使用相同的重名处理规则
+func (o *PlanOperator) Move(oldPath, newPath string) (string, error) {
+	if oldPath == newPath {
+		return oldPath, nil
+	}
+	newPath = ResolveConflictPath(newPath, func(path string) bool {
+		return o.Exists(path) && (o.occupied[path] || !IsSameFile(oldPath, path))
+	})
+	o.record(&PlanItem{OldPath: oldPath, NewPath: newPath})
+	return newPath, nil
+}
+
+// Replace 模拟移动并覆盖目标文件
+func (o *PlanOperator) Replace(oldPath, newPath string) error {
+	if oldPath == newPath {
+		return nil
+	}
+	o.record(&PlanItem{OldPath: oldPath, NewPath: newPath, Replace: o.Exists(newPath)})
+	return nil
+}
+
+// Copy 模拟复制文件,与CopyWithConflictResolution使用相同的重名处理规则
+func (o *PlanOperator) Copy(oldPath, newPath string, removeSource bool) (string, error) {
+	if oldPath == newPath {
+		return oldPath, nil
+	}
+	newPath = ResolveConflictPath(newPath, o.Exists)
+	o.record(&PlanItem{OldPath: oldPath, NewPath: newPath, Copy: !removeSource})
+	return newPath, nil
+}
+
+// Remove 模拟删除文件
+func (o *PlanOperator) Remove(path string) error {
+	o.record(&PlanItem{OldPath: path, Delete: true})
+	return nil
+}
+
+// record 记录计划项并更新模拟的文件占用情况
+func (o *PlanOperator) record(item *PlanItem) {
+	o.Items = append(o.Items, item)
+	if !item.Copy {
+		o.vacated[item.OldPath] = true
+		delete(o.occupied, item.OldPath)
+	}
+	if item.Delete {
+		return
+	}
+	o.occupied[item.NewPath] = true
+	delete(o.vacated, item.NewPath)
+}
+
+// Exists 判断按计划执行到当前步骤时路径是否已存在
+func (o *PlanOperator) Exists(path string) bool {
+	if o.occupied[path] {
+		return true
+	}
+	if o.vacated[path] {
+		return false
+	}
+	_, err := os.Stat(path)
+	return !os.IsNotExist(err)
+}
+
+// JournalOperator 在操作执行前写入待完成记录,实际操作完成后写入重命名日志,用于撤销
+type JournalOperator struct {
+	FileOperator
+	Journal *Journal
+}
+
+func NewJournalOperator(operator FileOperator, journal *Journal) *JournalOperator {
+	return &JournalOperator{FileOperator: operator, Journal: journal}
+}
+
+// Move 移动文件并记录日志
+func (o *JournalOperator) Move(oldPath, newPath string) (string, error) {
+	if err := o.pending(JournalOpMove, oldPath, newPath); err != nil {
+		return newPath, err
+	}
+	newPath, err := o.FileOperator.Move(oldPath, newPath)
+	if err != nil || oldPath == newPath {
+		return newPath, err
+	}
+	return newPath, o.record(JournalOpMove, oldPath, newPath)
+}
+
+// Replace 移动并覆盖目标文件,同时记录日志
+func (o *JournalOperator) Replace(oldPath, newPath string) error {
+	if err := o.pending(JournalOpReplace, oldPath, newPath); err != nil {
+		return err
+	}
+	if err := o.FileOperator.Replace(oldPath, newPath); err != nil || oldPath == newPath {
+		return err
+	}
+	return o.record(JournalOpReplace, oldPath, newPath)
+}
+
+// Copy 复制文件并记录日志,删除原文件时记录为移动
+func (o *JournalOperator) Copy(oldPath, newPath string, removeSource bool) (string, error) {
+	op := JournalOpCopy
+	if removeSource {
+		op = JournalOpMove
+	}
+	if err := o.pending(op, oldPath, newPath); err != nil {
+		return newPath, err
+	}
+	newPath, err := o.FileOperator.Copy(oldPath, newPath, removeSource)
+	if err != nil || oldPath == newPath {
+		return newPath, err
+	}
+	return newPath, o.record(op, oldPath, newPath)
+}
+
+// Remove 删除文件并在日志中记录文件内容,撤销时按内容恢复,仅用于较小的附属文件
+func (o *JournalOperator) Remove(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	hash, err := GetFileHash(context.Background(), path)
+	if err != nil {
+		return err
+	}
+	if path, err = filepath.Abs(path); err != nil {
+		return err
+	}
+	// 先记录文件内容再删除,避免删除后崩溃导致内容丢失
+	if err = o.Journal.PendingDelete(path, hash, data); err != nil {
+		return err
+	}
+	if err = o.FileOperator.Remove(path); err != nil {
+		return err
+	}
+	return o.Journal.Append(JournalOpDelete, path, "")
+}
+
+// pending 以绝对路径写入待完成记录,原路径与目标路径相同时不会移动文件,无需记录
+func (o *JournalOperator) pending(op, oldPath, newPath string) error {
+	if oldPath == newPath {
+		return nil
+	}
+	oldPath, err := filepath.Abs(oldPath)
+	if err != nil {
+		return err
+	}
+	if newPath, err = filepath.Abs(newPath); err != nil {
+		return err
+	}
+	return o.Journal.Pending(op, oldPath, newPath)
+}
+
+// record 以绝对路径记录日志,移动后的文件不再计算md5,只记录大小及修改时间
+func (o *JournalOperator) record(op, oldPath, newPath string) error {
+	oldPath, err := filepath.Abs(oldPath)
+	if err != nil {
+		return err
+	}
+	if newPath, err = filepath.Abs(newPath); err != nil {
+		return err
+	}
+	// 文件已移动,日志必须完整写入
+	return o.Journal.Append(op, oldPath, newPath)
+}
@@ -0,0 +1,157 @@
+package core
+
+// Options 运行选项,交互模式与命令行模式最终都汇总为该结构
+type Options struct {
+	Dir                     string             `json:"dir"`                         // 处理的目录路径
+	RenameType              string             `json:"rename_type"`                 // 重命名类型
+	MatchFailureHandlerType int                `json:"match_failure_handler_type"`  // 日期获取失败的处理方式
+	Template                string             `json:"template"`                    // 文件名模板
+	Target                  string             `json:"target,omitempty"`            // 整理模式的目标目录,为空时为处理的目录
+	FolderTemplate          string             `json:"folder_template,omitempty"`   // 整理模式的文件夹模板
+	DeleteSource            bool               `json:"delete_source,omitempty"`     // 导入时校验通过后删除原文件
+	PrefixRules             []*PrefixRule      `json:"prefix_rules,omitempty"`      // 自定义前缀规则,优先于默认规则
+	PrefixRuleSpecs         []string           `json:"-"`                           // 命令行中的前缀规则
+	ExtCase                 string             `json:"ext_case,omitempty"`          // 扩展名大小写处理方式
+	ExtAliases              map[string]string  `json:"ext_aliases,omitempty"`       // 扩展名别名
+	ExtAliasSpecs           []string           `json:"-"`                           // 命令行中的扩展名别名
+	FilenameRules           []*FilenameRule    `json:"filename_rules,omitempty"`    // 自定义文件名规则,优先于内置规则
+	FilenameRuleSpecs       []string           `json:"-"`                           // 命令行中的文件名规则
+	DateSources             []string           `json:"date_sources,omitempty"`      // 拍摄时间来源,按顺序使用
+	TimeZone                string             `json:"time_zone,omitempty"`         // 时区处理方式,为空时转换为本地时区
+	MetadataBackends        []string           `json:"metadata_backends,omitempty"` // 元数据读取方式,按顺序使用
+	TakeoutJSON             string             `json:"takeout_json,omitempty"`      // Takeout JSON附属文件的处理方式
+	MetadataProviders       []MetadataProvider `json:"-"`                           // 调用方注入的元数据读取方式,设置时代替MetadataBackends
+	Yes                     bool               `json:"-"`                           // 跳过操作确认
+	DryRun                  bool               `json:"-"`                           // 仅预览重命名计划,不修改文件
+	JournalPath             string             `json:"-"`                           // 重命名日志文件路径
+	ConfigPath              string             `json:"-"`                           // 配置文件路径
+}
+
+// GetTemplate 获取文件名模板,未设置时使用默认模板
+func (o *Options) GetTemplate() string {
+	if o.Template == "" {
+		return DefaultTemplate
+	}
+	return o.Template
+}
+
+// GetTarget 获取整理模式的目标目录,未设置时为处理的目录
+func (o *Options) GetTarget() string {
+	if o.Target == "" {
+		return o.Dir
+	}
+	return o.Target
+}
+
+// GetFolderTemplate 获取整理模式的文件夹模板,未设置时使用默认模板
+func (o *Options) GetFolderTemplate() string {
+	if o.FolderTemplate == "" {
+		return DefaultFolderTemplate
+	}
+	return o.FolderTemplate
+}
+
+// GetExtCase 获取扩展名大小写处理方式,未设置时使用默认方式
+func (o *Options) GetExtCase() string {
+	if o.ExtCase == "" {
+		return DefaultExtCase
+	}
+	return o.ExtCase
+}
+
+// GetExtPolicy 获取扩展名规范化策略
+func (o *Options) GetExtPolicy() *ExtPolicy {
+	return NewExtPolicy(o.GetExtCase(), o.ExtAliases)
+}
+
+// GetDateSources 获取拍摄时间来源,未设置时使用默认来源
+func (o *Options) GetDateSources() []string {
+	if len(o.DateSources) == 0 {
+		return DefaultDateSources
+	}
+	return o.DateSources
+}
+
+// GetMetadataReader 创建元数据读取器,注入了读取方式时直接使用,否则按名称使用已注册的读取方式
+func (o *Options) GetMetadataReader() (*MetadataReader, error) {
+	if len(o.MetadataProviders) > 0 {
+		return NewMetadataReader(o.MetadataProviders...), nil
+	}
+	return NewMetadataReaderByNames(o.GetMetadataBackends())
+}
+
+// GetMetadataBackends 获取元数据读取方式,未设置时使用默认顺序
+func (o *Options) GetMetadataBackends() []string {
+	if len(o.MetadataBackends) == 0 {
+		return DefaultMetadataBackends
+	}
+	return o.MetadataBackends
+}
+
+// GetTakeoutJSON 获取Takeout JSON附属文件的处理方式,未设置时使用默认方式
+func (o *Options) GetTakeoutJSON() string {
+	if o.TakeoutJSON == "" {
+		return DefaultTakeoutJSON
+	}
+	return o.TakeoutJSON
+}
+
+// ParseFlags 解析需要转换的命令行参数
+func (o *Options) ParseFlags() error {
+	for _, spec := range o.PrefixRuleSpecs {
+		rule, err := ParsePrefixRule(spec)
+		if err != nil {
+			return err
+		}
+		o.PrefixRules = append(o.PrefixRules, rule)
+	}
+	for _, spec := range o.FilenameRuleSpecs {
+		rule, err := ParseFilenameRule(spec)
+		if err != nil {
+			return err
+		}
+		o.FilenameRules = append(o.FilenameRules, rule)
+	}
+	for _, spec := range o.ExtAliasSpecs {
+		aliases, err := ParseExtAlias(spec)
+		if err != nil {
+			return err
+		}
+		if o.ExtAliases == nil {
+			o.ExtAliases = make(map[string]string)
+		}
+		for from, to := range aliases {
+			o.ExtAliases[from] = to
+		}
+	}
+	return nil
+}
+
+// Validate 在运行开始前校验选项
+func (o *Options) Validate() error {
+	if _, err := ParseTemplate(o.GetTemplate()); err != nil {
+		return err
+	}
+	if _, err := ParseFolderTemplate(o.GetFolderTemplate()); err != nil {
+		return err
+	}
+	if err := ValidatePrefixRules(o.PrefixRules); err != nil {
+		return err
+	}
+	if err := ValidateExtPolicy(o.GetExtCase(), o.ExtAliases); err != nil {
+		return err
+	}
+	if err := ValidateFilenameRules(o.FilenameRules); err != nil {
+		return err
+	}
+	if _, err := ParseTimeZonePolicy(o.TimeZone); err != nil {
+		return err
+	}
+	if err := ValidateDateSources(o.GetDateSources()); err != nil {
+		return err
+	}
+	if err := ValidateTakeoutJSON(o.GetTakeoutJSON()); err != nil {
+		return err
+	}
+	return ValidateMetadataBackends(o.GetMetadataBackends())
+}
@@ -0,0 +1,158 @@
+package core
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/thoas/go-funk"
+)
+
+// pngSignature PNG文件头
+var pngSignature = []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1a, '\n'}
+
+// pngCreationTimeKeyword 文本块中记录创建时间的关键字
+const pngCreationTimeKeyword = "Creation Time"
+
+// pngXMPKeyword iTXt中保存XMP的关键字
+const pngXMPKeyword = "XML:com.adobe.xmp"
+
+// errNotPNG 文件不是PNG格式
+var errNotPNG = errors.New("不是有效的PNG文件")
+
+// IsPNG 判断文件是否为PNG图片
+func IsPNG(path string) bool {
+	return funk.ContainsString([]string{".PNG"}, GetExt(path))
+}
+
+// pngMetadata PNG数据块中的元数据
+type pngMetadata struct {
+	exif         []byte        // eXIf块,以TIFF头开始
+	xmp          []byte        // iTXt中的XMP
+	creationTime *MetadataDate // 文本块中的Creation Time
+	modifyTime   *MetadataDate // tIME块中的最后修改时间
+}
+
+// readPNGMetadata 读取PNG的eXIf、tIME及tEXt/zTXt/iTXt数据块,跳过图像数据
+func readPNGMetadata(filename string) (*pngMetadata, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	header := make([]byte, 8)
+	if _, err = io.ReadFull(file, header); err != nil || !bytes.Equal(header, pngSignature) {
+		return nil, errNotPNG
+	}
+	metadata := &pngMetadata{}
+	for offset := int64(8); ; {
+		// 数据块: 长度(4) + 类型(4) + 数据 + CRC(4)
+		if _, err = file.ReadAt(header, offset); err != nil {
+			// 缺少IEND的文件读到末尾为止
+			return metadata, nil
+		}
+		length := int64(binary.BigEndian.Uint32(header[:4]))
+		chunkType := string(header[4:8])
+		if chunkType == "IEND" {
+			return metadata, nil
+		}
+		if funk.ContainsString([]string{"eXIf", "tIME", "tEXt", "zTXt", "iTXt"}, chunkType) && length <= metadataMaxChunkSize {
+			data := make([]byte, length)
+			if _, err = file.ReadAt(data, offset+8); err != nil {
+				return metadata, nil
+			}
+			metadata.parseChunk(chunkType, data)
+		}
+		offset += 12 + length
+	}
+}
+
+// parseChunk 解析一个数据块,无法解析时忽略
+func (m *pngMetadata) parseChunk(chunkType string, data []byte) {
+	switch chunkType {
+	case "eXIf":
+		m.exif = data
+	case "tIME":
+		// 年(2) + 月 + 日 + 时 + 分 + 秒,为UTC时间
+		if len(data) == 7 {
+			t := time.Date(int(binary.BigEndian.Uint16(data[:2])), time.Month(data[2]), int(data[3]), int(data[4]), int(data[5]), int(data[6]), 0, time.UTC)
+			if t.Year() > 1 && t.Month() == time.Month(data[2]) && t.Day() == int(data[3]) {
+				m.modifyTime = &MetadataDate{Time: t, OffsetKnown: true}
+			}
+		}
+	case "tEXt", "zTXt", "iTXt":
+		keyword, text, ok := parsePNGText(chunkType, data)
+		if !ok {
+			return
+		}
+		switch keyword {
+		case pngCreationTimeKeyword:
+			m.creationTime = parsePNGCreationTime(text)
+		case pngXMPKeyword:
+			m.xmp = []byte(text)
+		}
+	}
+}
+
+// parsePNGText 解析文本块,返回关键字及文本,压缩的文本自动解压
+func parsePNGText(chunkType string, data []byte) (string, string, bool) {
+	keyword, rest, ok := bytes.Cut(data, []byte{0})
+	if !ok {
+		return "", "", false
+	}
+	compressed := false
+	switch chunkType {
+	case "zTXt":
+		// 压缩方式(1) + 压缩的文本
+		if len(rest) < 1 {
+			return "", "", false
+		}
+		rest, compressed = rest[1:], true
+	case "iTXt":
+		// 压缩标志(1) + 压缩方式(1) + 语言\0 + 翻译后的关键字\0 + 文本
+		if len(rest) < 2 {
+			return "", "", false
+		}
+		compressed = rest[0] == 1
+		fields := bytes.SplitN(rest[2:], []byte{0}, 3)
+		if len(fields) != 3 {
+			return "", "", false
+		}
+		rest = fields[2]
+	}
+	if compressed {
+		reader, err := zlib.NewReader(bytes.NewReader(rest))
+		if err != nil {
+			return "", "", false
+		}
+		defer reader.Close()
+		if rest, err = io.ReadAll(io.LimitReader(reader, metadataMaxChunkSize)); err != nil {
+			return "", "", false
+		}
+	}
+	return string(keyword), string(rest), true
+}
+
+// parsePNGCreationTime 解析Creation Time,规范建议使用RFC 1123格式,实际也常见EXIF及ISO 8601格式,无法解析时返回nil
+func parsePNGCreationTime(value string) *MetadataDate {
+	value = strings.TrimSpace(value)
+	if date, err := ParseDateTime(value, time.Local); err == nil {
+		return date
+	}
+	for _, layout := range []string{time.RFC1123Z, time.RFC1123, time.RFC822Z, time.RFC822, "Mon, 2 Jan 2006 15:04:05 -0700", "2 Jan 2006 15:04:05 -0700"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return &MetadataDate{Time: t, OffsetKnown: true}
+		}
+	}
+	for _, layout := range []string{time.ANSIC, "Mon, 2 Jan 2006 15:04:05"} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return &MetadataDate{Time: t}
+		}
+	}
+	return nil
+}
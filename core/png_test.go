@@ -0,0 +1,100 @@
+package core
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testXMP 测试用的XMP数据包,拍摄时间为2023-04-05 06:07:08
+var testXMP = []byte(`<?xpacket begin="" id="W5M0MpCehiHzreSzNTczkc9d"?><x:xmpmeta xmlns:x="adobe:ns:meta/"><rdf:RDF>` +
+	`<rdf:Description exif:DateTimeOriginal="2023-04-05T06:07:08"/></rdf:RDF></x:xmpmeta><?xpacket end="w"?>`)
+
+// pngTestChunk 构造PNG数据块
+func pngTestChunk(chunkType string, data []byte) []byte {
+	chunk := binary.BigEndian.AppendUint32(nil, uint32(len(data)))
+	chunk = append(append(chunk, chunkType...), data...)
+	return binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(chunk[4:]))
+}
+
+// pngTestCompress 使用zlib压缩文本
+func pngTestCompress(text string) []byte {
+	var buf bytes.Buffer
+	writer := zlib.NewWriter(&buf)
+	_, _ = writer.Write([]byte(text))
+	_ = writer.Close()
+	return buf.Bytes()
+}
+
+// buildPNG 构造1x1的PNG,元数据块位于IHDR之后
+func buildPNG(chunks ...[]byte) []byte {
+	data := append(append([]byte{}, pngSignature...), pngTestChunk("IHDR", []byte{0, 0, 0, 1, 0, 0, 0, 1, 8, 0, 0, 0, 0})...)
+	for _, chunk := range chunks {
+		data = append(data, chunk...)
+	}
+	data = append(data, pngTestChunk("IDAT", pngTestCompress("\x00\x00"))...)
+	return append(data, pngTestChunk("IEND", nil)...)
+}
+
+func TestReadPNGMetadata(t *testing.T) {
+	exif := buildTIFF(binary.BigEndian, 42, nil, testRawExif)
+	oversized := binary.BigEndian.AppendUint32(nil, metadataMaxChunkSize+1)
+	tests := []struct {
+		name             string
+		data             []byte
+		wantExif         []byte
+		wantXMP          []byte
+		wantCreationTime time.Time
+		wantModifyTime   time.Time
+	}{
+		{"eXIf", buildPNG(pngTestChunk("eXIf", exif)), exif, nil, time.Time{}, time.Time{}},
+		{"tIME为UTC时间", buildPNG(pngTestChunk("tIME", []byte{0x07, 0xe7, 4, 5, 6, 7, 8})), nil, nil, time.Time{}, time.Date(2023, 4, 5, 6, 7, 8, 0, time.UTC)},
+		{"tIME日期无效", buildPNG(pngTestChunk("tIME", []byte{0x07, 0xe7, 2, 30, 6, 7, 8})), nil, nil, time.Time{}, time.Time{}},
+		{"tEXt中RFC 1123格式的Creation Time", buildPNG(pngTestChunk("tEXt", []byte("Creation Time\x00Wed, 05 Apr 2023 06:07:08 +0900"))),
+			nil, nil, time.Date(2023, 4, 5, 6, 7, 8, 0, time.FixedZone("", 9*3600)), time.Time{}},
+		{"zTXt中EXIF格式的Creation Time", buildPNG(pngTestChunk("zTXt", append([]byte("Creation Time\x00\x00"), pngTestCompress("2023:04:05 06:07:08")...))),
+			nil, nil, time.Date(2023, 4, 5, 6, 7, 8, 0, time.Local), time.Time{}},
+		{"iTXt中的XMP", buildPNG(pngTestChunk("iTXt", append([]byte(pngXMPKeyword+"\x00\x00\x00\x00\x00"), testXMP...))), nil, testXMP, time.Time{}, time.Time{}},
+		{"压缩的iTXt", buildPNG(pngTestChunk("iTXt", append([]byte(pngXMPKeyword+"\x00\x01\x00\x00\x00"), pngTestCompress(string(testXMP))...))), nil, testXMP, time.Time{}, time.Time{}},
+		{"超出长度上限的数据块", append(buildPNG()[:33], append(oversized, "eXIf"...)...), nil, nil, time.Time{}, time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "image.png")
+			if err := os.WriteFile(path, tt.data, 0644); err != nil {
+				t.Fatal(err)
+			}
+			metadata, err := readPNGMetadata(path)
+			if err != nil {
+				t.Fatalf("readPNGMetadata() error = %v", err)
+			}
+			if !bytes.Equal(metadata.exif, tt.wantExif) || !bytes.Equal(metadata.xmp, tt.wantXMP) {
+				t.Errorf("exif = %x, xmp = %q, want %x, %q", metadata.exif, metadata.xmp, tt.wantExif, tt.wantXMP)
+			}
+			if (metadata.creationTime == nil) != tt.wantCreationTime.IsZero() ||
+				(metadata.creationTime != nil && !metadata.creationTime.Time.Equal(tt.wantCreationTime)) {
+				t.Errorf("creationTime = %v, want %v", metadata.creationTime, tt.wantCreationTime)
+			}
+			if (metadata.modifyTime == nil) != tt.wantModifyTime.IsZero() ||
+				(metadata.modifyTime != nil && !metadata.modifyTime.Time.Equal(tt.wantModifyTime)) {
+				t.Errorf("modifyTime = %v, want %v", metadata.modifyTime, tt.wantModifyTime)
+			}
+		})
+	}
+}
+
+func TestReadPNGMetadataNotPNG(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "image.png")
+	if err := os.WriteFile(path, []byte("GIF89a"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readPNGMetadata(path); !errors.Is(err, errNotPNG) {
+		t.Errorf("readPNGMetadata() error = %v, want %v", err, errNotPNG)
+	}
+}
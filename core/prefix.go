@@ -0,0 +1,204 @@
+package core
+
+import (
+	"fmt"
+	"image"
+	_ "image/gif"  // 注册gif解码器,用于读取图片尺寸
+	_ "image/jpeg" // 注册jpeg解码器,用于读取图片尺寸
+	_ "image/png"  // 注册png解码器,用于读取图片尺寸
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/thoas/go-funk"
+)
+
+// 媒体类型
+const (
+	MediaKindImage      = "image"      // 图片
+	MediaKindVideo      = "video"      // 视频
+	MediaKindRaw        = "raw"        // RAW格式图片
+	MediaKindScreenshot = "screenshot" // 截图
+	MediaKindPanorama   = "panorama"   // 全景照片
+	MediaKindOther      = "other"      // 其他文件
+)
+
+// MediaKindTextMap 媒体类型说明
+var MediaKindTextMap = map[string]string{
+	MediaKindImage:      "图片",
+	MediaKindVideo:      "视频",
+	MediaKindRaw:        "RAW格式图片",
+	MediaKindScreenshot: "截图(文件名包含Screenshot/截屏等,或没有相机信息的PNG图片)",
+	MediaKindPanorama:   "全景照片(文件名包含PANO,或宽度不小于高度的2倍)",
+	MediaKindOther:      "其他文件",
+}
+
+// screenshotKeywords 截图文件名中常见的关键字
+var screenshotKeywords = []string{"screenshot", "screen shot", "screen_shot", "截屏", "截图", "屏幕快照", "屏幕截图"}
+
+// PrefixRule 前缀规则,扩展名与媒体类型均满足时使用该前缀,未设置的条件视为满足
+type PrefixRule struct {
+	Prefix     string   `yaml:"prefix" json:"prefix"`                   // 前缀
+	Extensions []string `yaml:"extensions" json:"extensions,omitempty"` // 扩展名,满足其一即可
+	Kind       string   `yaml:"kind" json:"kind,omitempty"`             // 媒体类型
+}
+
+// DefaultPrefixRules 默认前缀规则,始终排在自定义规则之后
+var DefaultPrefixRules = []*PrefixRule{
+	{Prefix: "IMG", Kind: MediaKindImage},
+	{Prefix: "VID", Kind: MediaKindVideo},
+	{Prefix: "FIL", Kind: MediaKindOther},
+}
+
+// ParsePrefixRule 解析命令行中的前缀规则,格式为 前缀=条件1,条件2,以.开头的条件为扩展名,否则为媒体类型
+func ParsePrefixRule(spec string) (*PrefixRule, error) {
+	prefix, conditions, ok := strings.Cut(spec, "=")
+	if !ok || conditions == "" {
+		return nil, fmt.Errorf("前缀规则%s格式错误,正确格式如RAW=.CR2,.NEF或PANO=panorama", spec)
+	}
+	rule := &PrefixRule{Prefix: strings.TrimSpace(prefix)}
+	for _, condition := range strings.Split(conditions, ",") {
+		condition = strings.TrimSpace(condition)
+		if strings.HasPrefix(condition, ".") {
+			rule.Extensions = append(rule.Extensions, condition)
+			continue
+		}
+		if rule.Kind != "" {
+			return nil, fmt.Errorf("前缀规则%s中只能指定一个媒体类型", spec)
+		}
+		rule.Kind = condition
+	}
+	return rule, nil
+}
+
+// ValidatePrefixRules 校验前缀规则,并将扩展名统一为大写
+func ValidatePrefixRules(rules []*PrefixRule) error {
+	for _, rule := range rules {
+		if strings.ContainsAny(rule.Prefix, templateIllegalChars) {
+			return fmt.Errorf("前缀%s中不能包含以下字符:%s", rule.Prefix, templateIllegalChars)
+		}
+		if rule.Kind != "" && MediaKindTextMap[rule.Kind] == "" {
+			return fmt.Errorf("前缀%s的媒体类型%s不存在,可用类型:%s", rule.Prefix, rule.Kind, strings.Join(MediaKinds(), ","))
+		}
+		if rule.Kind == "" && len(rule.Extensions) == 0 {
+			return fmt.Errorf("前缀%s至少需要指定扩展名或媒体类型", rule.Prefix)
+		}
+		for i, ext := range rule.Extensions {
+			rule.Extensions[i] = strings.ToUpper("." + strings.TrimPrefix(ext, "."))
+		}
+	}
+	return nil
+}
+
+// MediaKinds 返回排序后的全部媒体类型
+func MediaKinds() []string {
+	kinds := funk.Keys(MediaKindTextMap).([]string)
+	sort.Strings(kinds)
+	return kinds
+}
+
+// String 规则的文本形式,与命令行格式一致
+func (r *PrefixRule) String() string {
+	conditions := append([]string{}, r.Extensions...)
+	if r.Kind != "" {
+		conditions = append(conditions, r.Kind)
+	}
+	return r.Prefix + "=" + strings.Join(conditions, ",")
+}
+
+// Match 判断文件是否满足规则
+func (r *PrefixRule) Match(file *mediaFile) bool {
+	if len(r.Extensions) > 0 && !funk.ContainsString(r.Extensions, GetExt(file.path)) {
+		return false
+	}
+	return r.Kind == "" || file.isKind(r.Kind)
+}
+
+// MatchPrefix 按顺序匹配规则,返回首个满足条件的前缀
+func MatchPrefix(rules []*PrefixRule, file *mediaFile) string {
+	for _, rule := range rules {
+		if rule.Match(file) {
+			return rule.Prefix
+		}
+	}
+	return ""
+}
+
+// mediaFile 命名时用到的文件信息,相机信息及图片尺寸仅在需要时读取
+type mediaFile struct {
+	path         string
+	cameraLoaded bool
+	cameraMake   string
+	cameraModel  string
+	sizeLoaded   bool
+	width        int
+	height       int
+}
+
+func newMediaFile(path string) *mediaFile {
+	return &mediaFile{path: path}
+}
+
+// camera 获取相机厂商及型号,读取失败时留空
+func (f *mediaFile) camera() (string, string) {
+	if !f.cameraLoaded {
+		f.cameraLoaded = true
+		f.cameraMake, f.cameraModel, _ = GetExifCamera(f.path)
+		// EXIF中没有相机信息时使用XMP,如扫描件或导出的图片
+		if f.cameraMake == "" && f.cameraModel == "" {
+			f.cameraMake, f.cameraModel = GetXMPCamera(f.path)
+		}
+	}
+	return f.cameraMake, f.cameraModel
+}
+
+// size 获取图片尺寸,无法解析时为0
+func (f *mediaFile) size() (int, int) {
+	if !f.sizeLoaded {
+		f.sizeLoaded = true
+		if file, err := os.Open(f.path); err == nil {
+			if config, _, err := image.DecodeConfig(file); err == nil {
+				f.width, f.height = config.Width, config.Height
+			}
+			file.Close()
+		}
+	}
+	return f.width, f.height
+}
+
+// isKind 判断文件是否属于指定媒体类型
+func (f *mediaFile) isKind(kind string) bool {
+	name := strings.ToLower(filepath.Base(f.path))
+	switch kind {
+	case MediaKindImage:
+		return IsImage(f.path)
+	case MediaKindVideo:
+		return IsVideo(f.path)
+	case MediaKindRaw:
+		return IsRaw(f.path)
+	case MediaKindScreenshot:
+		for _, keyword := range screenshotKeywords {
+			if strings.Contains(name, keyword) {
+				return true
+			}
+		}
+		if GetExt(f.path) != ".PNG" {
+			return false
+		}
+		cameraMake, cameraModel := f.camera()
+		return cameraMake == "" && cameraModel == ""
+	case MediaKindPanorama:
+		if !IsImage(f.path) {
+			return false
+		}
+		if strings.Contains(name, "pano") {
+			return true
+		}
+		width, height := f.size()
+		return height > 0 && width >= 2*height
+	case MediaKindOther:
+		return !IsImage(f.path) && !IsVideo(f.path)
+	}
+	return false
+}
@@ -1,11 +1,18 @@
 package core
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"hyue418/go-rename/common"
 	"os"
+	"os/signal"
+	"path/filepath"
 	"sort"
+	"strings"
 	"sync"
+	"syscall"
+	"time"
 
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
@@ -21,6 +28,8 @@ const (
 	RenameTypeImage         = "rename-type-image"           // 重命名图片
 	RenameTypeVideo         = "rename-type-video"           // 重命名视频
 	RenameTypeImageAndVideo = "rename-type-image-and-video" // 重命名图片/视频
+	RenameTypeOrganize      = "rename-type-organize"        // 重命名图片/视频并按拍摄时间整理到分层文件夹
+	RenameTypeImport        = "rename-type-import"          // 将图片/视频复制到图库并按拍摄时间整理
 	RenameTypeFileByHash    = "rename-type-file-by-hash"    // 根据md5重命名照片/视频文件(用于文件去重)
 )
 
@@ -29,6 +38,8 @@ var RenameTypeNumberMap = map[int]string{
 	1:  RenameTypeImage,
 	2:  RenameTypeVideo,
 	3:  RenameTypeImageAndVideo,
+	4:  RenameTypeOrganize,
+	5:  RenameTypeImport,
 	99: RenameTypeFileByHash,
 }
 
@@ -37,6 +48,8 @@ var RenameTypeTextMap = map[string]string{
 	RenameTypeImage:         "根据拍摄时间重命名图片文件",
 	RenameTypeVideo:         "根据拍摄时间重命名视频文件",
 	RenameTypeImageAndVideo: "根据拍摄时间重命名图片/视频文件",
+	RenameTypeOrganize:      "根据拍摄时间重命名图片/视频文件,并移至目标目录中按日期分层的文件夹(如2025/2025-06)",
+	RenameTypeImport:        "【导入】将图片/视频文件复制到图库目录中按日期分层的文件夹,逐个校验md5,不修改原文件",
 	RenameTypeFileByHash:    "【文件去重】根据md5重命名照片/视频文件,内容完全相同的文件将只保留一个",
 }
 
@@ -45,6 +58,7 @@ const (
 	MatchFailureHandlerTypeIgnore               = 1 + iota // 忽略
 	MatchFailureHandlerTypeUseFileCreationTime             // 使用文件创建时间
 	MatchFailureHandlerTypeMoveToUnknownDateDir            // 移至unknown-date文件夹
+	MatchFailureHandlerTypeUseFilenameDate                 // 从文件名推断时间,推断不出时使用文件创建时间
 )
 
 // MatchFailureHandlerTypeMap 编号与日期获取失败的处理方式映射
@@ -52,157 +66,489 @@ var MatchFailureHandlerTypeMap = map[int]int{
 	1: MatchFailureHandlerTypeIgnore,
 	2: MatchFailureHandlerTypeUseFileCreationTime,
 	3: MatchFailureHandlerTypeMoveToUnknownDateDir,
+	4: MatchFailureHandlerTypeUseFilenameDate,
 }
 
 var MatchFailureHandlerTypeTextMap = map[int]string{
 	MatchFailureHandlerTypeIgnore:               "忽略此类文件,不处理",
 	MatchFailureHandlerTypeUseFileCreationTime:  "使用文件的创建时间/修改时间(哪个时间早用哪个)替代拍摄时间",
 	MatchFailureHandlerTypeMoveToUnknownDateDir: "统一将这部分文件移至unknown-date文件夹,不修改文件名",
+	MatchFailureHandlerTypeUseFilenameDate:      "从文件名推断时间(如mmexport1690000000000.jpg、IMG-20230101-WA0001.jpg),推断不出时使用文件的创建时间/修改时间",
+}
+
+// MatchFailureHandlerTypeNameMap 命令行参数名称与日期获取失败的处理方式映射
+var MatchFailureHandlerTypeNameMap = map[string]int{
+	"ignore":        MatchFailureHandlerTypeIgnore,
+	"creation-time": MatchFailureHandlerTypeUseFileCreationTime,
+	"unknown-date":  MatchFailureHandlerTypeMoveToUnknownDateDir,
+	"filename":      MatchFailureHandlerTypeUseFilenameDate,
 }
 
 // RenameStrategy 重命名策略器
 type RenameStrategy interface {
-	CountFiles(dir string) (int64, error)
-	Rename(dir string, bar *mpb.Bar) error
+	CountFiles(ctx context.Context, dir string) (int64, error)
+	Rename(ctx context.Context, dir string, bar *mpb.Bar) error
+}
+
+// NewRenameStrategy 根据运行选项创建重命名策略器
+func NewRenameStrategy(opts *Options, renamer *Renamer) (RenameStrategy, error) {
+	switch opts.RenameType {
+	case RenameTypeImage:
+		return NewRenameImage(renamer), nil
+	case RenameTypeVideo:
+		return NewRenameVideo(renamer), nil
+	case RenameTypeImageAndVideo:
+		return NewRenameImageAndVideo(renamer), nil
+	case RenameTypeOrganize:
+		return NewRenameOrganize(renamer), nil
+	case RenameTypeImport:
+		return NewRenameImport(renamer), nil
+	case RenameTypeFileByHash:
+		return NewRenameFileByHash(renamer), nil
+	}
+	return nil, fmt.Errorf("不支持的重命名类型:%s", opts.RenameType)
 }
 
 // Execute 执行
 func Execute() {
-	var dir, renameType string
-	var matchFailureHandlerType int
-	var numbers []int
+	opts := &Options{}
 	cmd := &cobra.Command{
-		Run: func(cmd *cobra.Command, args []string) {
-			var renameTypeNum, matchFailureHandlerTypeNum int
-			var inputPassed bool
-			var err error
-			for !inputPassed {
-				fmt.Print("请输入要处理的目录路径: ")
-				_, err = fmt.Scanln(&dir)
-				if err != nil || dir == "" {
-					common.PrintError("输入错误,请输入正确的目录路径")
-					continue
-				}
-				if info, err := os.Stat(dir); os.IsNotExist(err) {
-					common.PrintError("输入错误,目录不存在")
-					continue
-				} else if !info.IsDir() {
-					common.PrintError("输入错误,请输入正确的目录路径")
-					continue
-				}
-				inputPassed = true
-			}
-			common.PrintDividingLine()
-			color.New(color.FgBlue).Add(color.Bold).Println("【操作类型】")
-			numbers = funk.Keys(RenameTypeNumberMap).([]int)
-			sort.Ints(numbers)
-			for _, v := range numbers {
-				fmt.Printf("%d.%s\n", v, RenameTypeTextMap[RenameTypeNumberMap[v]])
+		Use:   "go-rename",
+		Short: "照片视频重命名工具,不带参数运行时进入交互模式",
+		Args:  cobra.NoArgs,
+		// 错误统一由PrintError输出
+		SilenceErrors: true,
+		SilenceUsage:  true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if err := opts.ParseFlags(); err != nil {
+				return err
 			}
-			fmt.Println()
-			inputPassed = false
-			for !inputPassed {
-				fmt.Print("请输入编号:")
-				_, err = fmt.Scanln(&renameTypeNum)
-				if err != nil {
-					common.PrintError("输入错误,请输入正确的编号")
-					continue
-				}
-				renameType = RenameTypeNumberMap[renameTypeNum]
-				if renameType == "" {
-					common.PrintError("输入错误,请输入正确的编号")
-					continue
-				}
-				inputPassed = true
-			}
-			common.PrintDividingLine()
-			if renameType != RenameTypeFileByHash {
-				color.New(color.FgBlue).Add(color.Bold).Println("【部分文件可能没有拍摄日期,想如何处理?】")
-				numbers = funk.Keys(MatchFailureHandlerTypeMap).([]int)
-				sort.Ints(numbers)
-				for _, v := range numbers {
-					fmt.Printf("%d.%s\n", v, MatchFailureHandlerTypeTextMap[v])
-				}
-				fmt.Println()
-				inputPassed = false
-				for !inputPassed {
-					fmt.Print("请输入编号:")
-					_, err = fmt.Scanln(&matchFailureHandlerTypeNum)
-					if err != nil {
-						common.PrintError("输入错误,请输入正确的编号")
-						continue
-					}
-					matchFailureHandlerType = MatchFailureHandlerTypeMap[matchFailureHandlerTypeNum]
-					if matchFailureHandlerType == 0 {
-						common.PrintError("输入错误,请输入正确的编号")
-						continue
-					}
-					inputPassed = true
-				}
-			}
-			common.PrintDividingLine()
-			color.New(color.FgBlue).Add(color.Bold).Println("【操作确认】")
-			color.New().Add(color.FgRed).Printf("处理的目录路径: ")
-			color.New().Add(color.FgRed).Add(color.Underline).Printf("%s\n", dir)
-			color.New().Add(color.FgRed).Printf("处理方式: ")
-			color.New().Add(color.FgRed).Add(color.Underline).Printf("%s", RenameTypeTextMap[renameType])
-			switch renameType {
-			case RenameTypeImage:
-				color.New().Add(color.FgRed).Printf("\n该目录下(包含子目录)所有符合条件的图片文件将重命名为[IMG_20250606_121601.XXX]的格式")
-			case RenameTypeVideo:
-				color.New().Add(color.FgRed).Printf("\n该目录下(包含子目录)所有符合条件的视频文件将重命名为[VID_20250606_121601.XXX]的格式")
-			case RenameTypeImageAndVideo:
-				color.New().Add(color.FgRed).Printf("\n该目录下(包含子目录)所有符合条件的图片及视频文件将重命名为[IMG_20250606_121601.XXX]和[VID_20250606_121601.XXX]的格式")
-			case RenameTypeFileByHash:
-				color.New().Add(color.FgRed).Printf("\n该目录下(包含子目录)的图片及视频文件将重命名为md5的格式,md5值相同的将合并为一个文件")
-			}
-			fmt.Print("\n\n")
-			confirmType := 2
-			var confirmText string
-			for confirmType == 2 {
-				fmt.Print("确认处理吗? y是n否\n请输入y/n: ")
-				_, err = fmt.Scanln(&confirmText)
-				if err != nil {
-					common.PrintError("输入错误，请输入y/n")
-					continue
-				}
-				switch confirmText {
-				case "Y", "y":
-					confirmType = 1
-				case "N", "n":
-					common.PrintError("结束运行")
-					os.Exit(1)
-				default:
-					common.PrintError("输入错误,请输入y/n")
-					continue
-				}
+			config, err := LoadConfig(opts.ConfigPath)
+			if err != nil {
+				return err
 			}
+			config.Apply(cmd, opts)
+			return opts.Validate()
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			RunWizard(opts)
+			Process(opts)
 		},
 	}
+	cmd.PersistentFlags().StringVar(&opts.JournalPath, "journal", "", "重命名日志文件路径,默认为~/"+AppDir+"/"+JournalFileName)
+	cmd.PersistentFlags().StringVar(&opts.ConfigPath, "config", "", "配置文件路径,默认为~/"+AppDir+"/"+ConfigFileName)
+	cmd.PersistentFlags().StringVar(&opts.Template, "template", DefaultTemplate, "文件名模板,可用变量:\n"+templateTokenUsage())
+	cmd.PersistentFlags().StringArrayVar(&opts.PrefixRuleSpecs, "prefix-rule", nil, "自定义前缀规则,可重复指定,优先于默认的IMG/VID/FIL,如RAW=.CR2,.NEF或PANO=panorama\n可用媒体类型:\n"+mediaKindUsage())
+	cmd.PersistentFlags().StringVar(&opts.ExtCase, "ext-case", DefaultExtCase, "扩展名大小写处理方式:\n"+extCaseUsage())
+	cmd.PersistentFlags().StringArrayVar(&opts.ExtAliasSpecs, "ext-alias", nil, "扩展名别名,可重复指定,如.jpeg,.jfif=.jpg或.tif=.tiff")
+	cmd.PersistentFlags().StringArrayVar(&opts.FilenameRuleSpecs, "filename-rule", nil, "自定义文件名规则,可重复指定,优先于内置规则,格式为 名称=正则表达式\n正则表达式通过命名分组year、month、day、hour、minute、second、ms或unix、unix_ms提取时间")
+	cmd.PersistentFlags().StringSliceVar(&opts.DateSources, "date-source", nil, "拍摄时间来源,多个用逗号分隔,按顺序使用首个能获取到的时间,默认为"+strings.Join(DefaultDateSources, ",")+"\n"+dateSourceUsage())
+	cmd.PersistentFlags().StringVar(&opts.TimeZone, "timezone", DefaultTimeZone, "文件名中时间的时区,可用时区名称(如Asia/Shanghai、UTC)、偏移(如+08:00)或:\n"+timeZoneUsage())
+	cmd.PersistentFlags().StringSliceVar(&opts.MetadataBackends, "metadata-backend", nil, "元数据读取方式,多个用逗号分隔,按顺序使用直到读取到拍摄时间,默认为"+strings.Join(DefaultMetadataBackends, ",")+"\n"+metadataBackendUsage())
+	cmd.PersistentFlags().StringVar(&opts.TakeoutJSON, "takeout-json", DefaultTakeoutJSON, "Google Takeout导出的JSON附属文件(photo.jpg.json等)的处理方式:\n"+takeoutJSONUsage())
+	for _, subCmd := range newRenameCommands(opts) {
+		cmd.AddCommand(subCmd)
+	}
+	cmd.AddCommand(newUndoCommand(opts))
+	cmd.AddCommand(newResumeCommand(opts))
 	if err := cmd.Execute(); err != nil {
 		common.PrintError(err.Error())
 		os.Exit(1)
 	}
+}
+
+// RunWizard 交互式收集运行选项
+func RunWizard(opts *Options) {
+	var renameTypeNum, matchFailureHandlerTypeNum int
+	var numbers []int
+	var inputPassed bool
+	var err error
+	for !inputPassed {
+		fmt.Print("请输入要处理的目录路径: ")
+		_, err = fmt.Scanln(&opts.Dir)
+		if err != nil || opts.Dir == "" {
+			common.PrintError("输入错误,请输入正确的目录路径")
+			continue
+		}
+		if err = CheckDir(opts.Dir); err != nil {
+			common.PrintError("输入错误," + err.Error())
+			continue
+		}
+		inputPassed = true
+	}
 	common.PrintDividingLine()
-	color.New(color.FgBlue).Add(color.Bold).Println("正在统计文件数量,请稍后...")
-	var renameStrategy RenameStrategy
-	switch renameType {
+	color.New(color.FgBlue).Add(color.Bold).Println("【操作类型】")
+	numbers = funk.Keys(RenameTypeNumberMap).([]int)
+	sort.Ints(numbers)
+	for _, v := range numbers {
+		fmt.Printf("%d.%s\n", v, RenameTypeTextMap[RenameTypeNumberMap[v]])
+	}
+	fmt.Println()
+	inputPassed = false
+	for !inputPassed {
+		fmt.Print("请输入编号:")
+		_, err = fmt.Scanln(&renameTypeNum)
+		if err != nil {
+			common.PrintError("输入错误,请输入正确的编号")
+			continue
+		}
+		opts.RenameType = RenameTypeNumberMap[renameTypeNum]
+		if opts.RenameType == "" {
+			common.PrintError("输入错误,请输入正确的编号")
+			continue
+		}
+		inputPassed = true
+	}
+	switch opts.RenameType {
+	case RenameTypeOrganize:
+		common.PrintDividingLine()
+		inputPassed = false
+		for !inputPassed {
+			opts.Target = ""
+			fmt.Print("请输入整理后的目标目录路径(直接回车表示处理的目录): ")
+			// 直接回车时Scanln返回unexpected newline,使用处理的目录
+			if _, err = fmt.Scanln(&opts.Target); err != nil && opts.Target != "" {
+				common.PrintError("输入错误,请输入正确的目录路径")
+				continue
+			}
+			inputPassed = true
+		}
+	case RenameTypeImport:
+		common.PrintDividingLine()
+		inputPassed = false
+		for !inputPassed {
+			fmt.Print("请输入图库目录路径: ")
+			_, err = fmt.Scanln(&opts.Target)
+			if err != nil || opts.Target == "" {
+				common.PrintError("输入错误,请输入正确的目录路径")
+				continue
+			}
+			if err = CheckImportTarget(opts.Dir, opts.Target); err != nil {
+				common.PrintError("输入错误," + err.Error())
+				continue
+			}
+			inputPassed = true
+		}
+	}
+	common.PrintDividingLine()
+	if opts.RenameType != RenameTypeFileByHash {
+		color.New(color.FgBlue).Add(color.Bold).Println("【部分文件可能没有拍摄日期,想如何处理?】")
+		numbers = funk.Keys(MatchFailureHandlerTypeMap).([]int)
+		sort.Ints(numbers)
+		for _, v := range numbers {
+			fmt.Printf("%d.%s\n", v, MatchFailureHandlerTypeTextMap[v])
+		}
+		fmt.Println()
+		inputPassed = false
+		for !inputPassed {
+			fmt.Print("请输入编号:")
+			_, err = fmt.Scanln(&matchFailureHandlerTypeNum)
+			if err != nil {
+				common.PrintError("输入错误,请输入正确的编号")
+				continue
+			}
+			opts.MatchFailureHandlerType = MatchFailureHandlerTypeMap[matchFailureHandlerTypeNum]
+			if opts.MatchFailureHandlerType == 0 {
+				common.PrintError("输入错误,请输入正确的编号")
+				continue
+			}
+			inputPassed = true
+		}
+	}
+}
+
+// Confirm 打印操作确认信息,未指定跳过确认时等待用户输入
+func Confirm(opts *Options) {
+	common.PrintDividingLine()
+	color.New(color.FgBlue).Add(color.Bold).Println("【操作确认】")
+	color.New().Add(color.FgRed).Printf("处理的目录路径: ")
+	color.New().Add(color.FgRed).Add(color.Underline).Printf("%s\n", opts.Dir)
+	color.New().Add(color.FgRed).Printf("处理方式: ")
+	color.New().Add(color.FgRed).Add(color.Underline).Printf("%s", RenameTypeTextMap[opts.RenameType])
+	if opts.RenameType == RenameTypeOrganize || opts.RenameType == RenameTypeImport {
+		color.New().Add(color.FgRed).Printf("\n目标目录路径: ")
+		color.New().Add(color.FgRed).Add(color.Underline).Printf("%s", opts.GetTarget())
+		color.New().Add(color.FgRed).Printf("\n文件夹模板: ")
+		color.New().Add(color.FgRed).Add(color.Underline).Printf("%s", opts.GetFolderTemplate())
+	}
+	if opts.RenameType == RenameTypeImport && opts.DeleteSource {
+		color.New().Add(color.FgRed).Add(color.Bold).Printf("\n校验通过后将删除原文件")
+	}
+	if opts.RenameType != RenameTypeFileByHash {
+		color.New().Add(color.FgRed).Printf("\n无拍摄日期的文件: ")
+		color.New().Add(color.FgRed).Add(color.Underline).Printf("%s", MatchFailureHandlerTypeTextMap[opts.MatchFailureHandlerType])
+		if timeZone, err := ParseTimeZonePolicy(opts.TimeZone); err == nil {
+			color.New().Add(color.FgRed).Printf("\n时区: ")
+			color.New().Add(color.FgRed).Add(color.Underline).Printf("%s", timeZone)
+		}
+		if opts.GetTakeoutJSON() == TakeoutJSONDelete {
+			color.New().Add(color.FgRed).Add(color.Bold).Printf("\nTakeout JSON附属文件将在原文件处理后删除")
+		}
+	}
+	if opts.RenameType != RenameTypeFileByHash {
+		color.New().Add(color.FgRed).Printf("\n文件名模板: ")
+		color.New().Add(color.FgRed).Add(color.Underline).Printf("%s", opts.GetTemplate())
+		if len(opts.PrefixRules) > 0 {
+			rules := make([]string, 0, len(opts.PrefixRules))
+			for _, rule := range opts.PrefixRules {
+				rules = append(rules, rule.String())
+			}
+			color.New().Add(color.FgRed).Printf("\n自定义前缀规则: ")
+			color.New().Add(color.FgRed).Add(color.Underline).Printf("%s", strings.Join(rules, "; "))
+		}
+		if len(opts.FilenameRules) > 0 {
+			rules := make([]string, 0, len(opts.FilenameRules))
+			for _, rule := range opts.FilenameRules {
+				rules = append(rules, rule.String())
+			}
+			color.New().Add(color.FgRed).Printf("\n文件名规则: ")
+			color.New().Add(color.FgRed).Add(color.Underline).Printf("%s", strings.Join(rules, " "))
+		}
+		if len(opts.DateSources) > 0 {
+			color.New().Add(color.FgRed).Printf("\n拍摄时间来源: ")
+			color.New().Add(color.FgRed).Add(color.Underline).Printf("%s", strings.Join(opts.DateSources, ","))
+		}
+		if len(opts.MetadataBackends) > 0 {
+			color.New().Add(color.FgRed).Printf("\n元数据读取方式: ")
+			color.New().Add(color.FgRed).Add(color.Underline).Printf("%s", strings.Join(opts.MetadataBackends, ","))
+		}
+	}
+	switch opts.RenameType {
 	case RenameTypeImage:
-		renameStrategy = NewRenameImage(matchFailureHandlerType)
+		color.New().Add(color.FgRed).Printf("\n该目录下(包含子目录)所有符合条件的图片文件将重命名为[%s]的格式", exampleFileName(opts, "DSC_0001.JPG"))
 	case RenameTypeVideo:
-		renameStrategy = NewRenameVideo(matchFailureHandlerType)
+		color.New().Add(color.FgRed).Printf("\n该目录下(包含子目录)所有符合条件的视频文件将重命名为[%s]的格式", exampleFileName(opts, "MVI_0001.MP4"))
 	case RenameTypeImageAndVideo:
-		renameStrategy = NewRenameImageAndVideo(matchFailureHandlerType)
+		color.New().Add(color.FgRed).Printf("\n该目录下(包含子目录)所有符合条件的图片及视频文件将重命名为[%s]和[%s]的格式", exampleFileName(opts, "DSC_0001.JPG"), exampleFileName(opts, "MVI_0001.MP4"))
+	case RenameTypeOrganize:
+		color.New().Add(color.FgRed).Printf("\n该目录下(包含子目录)所有符合条件的图片及视频文件将移至目标目录中的[%s]和[%s]", exampleFileName(opts, "DSC_0001.JPG"), exampleFileName(opts, "MVI_0001.MP4"))
+	case RenameTypeImport:
+		color.New().Add(color.FgRed).Printf("\n该目录下(包含子目录)所有符合条件的图片及视频文件将复制到图库目录中的[%s]和[%s]", exampleFileName(opts, "DSC_0001.JPG"), exampleFileName(opts, "MVI_0001.MP4"))
 	case RenameTypeFileByHash:
-		renameStrategy = NewRenameFileByHash()
-	default:
+		color.New().Add(color.FgRed).Printf("\n该目录下(包含子目录)的图片及视频文件将重命名为md5的格式,md5值相同的将合并为一个文件")
+	}
+	fmt.Print("\n\n")
+	if opts.Yes || opts.DryRun {
+		return
+	}
+	confirmType := 2
+	var confirmText string
+	for confirmType == 2 {
+		fmt.Print("确认处理吗? y是n否p预览重命名计划\n请输入y/n/p: ")
+		_, err := fmt.Scanln(&confirmText)
+		if err != nil {
+			common.PrintError("输入错误，请输入y/n/p")
+			continue
+		}
+		switch confirmText {
+		case "Y", "y":
+			confirmType = 1
+		case "N", "n":
+			common.PrintError("结束运行")
+			os.Exit(1)
+		case "P", "p":
+			if err = Preview(opts); err != nil {
+				common.PrintError(err.Error())
+				os.Exit(1)
+			}
+			common.PrintDividingLine()
+			continue
+		default:
+			common.PrintError("输入错误,请输入y/n/p")
+			continue
+		}
+	}
+}
+
+// Process 按运行选项执行重命名
+func Process(opts *Options) {
+	Confirm(opts)
+	if opts.DryRun {
+		if err := Preview(opts); err != nil {
+			common.PrintError(err.Error())
+			os.Exit(1)
+		}
 		return
 	}
-	fileCount, err := renameStrategy.CountFiles(dir)
+	dir, err := filepath.Abs(opts.Dir)
+	if err != nil {
+		common.PrintError(err.Error())
+		os.Exit(1)
+	}
+	opts.Dir = dir
+	if opts.RenameType == RenameTypeOrganize || opts.RenameType == RenameTypeImport {
+		if opts.Target, err = filepath.Abs(opts.GetTarget()); err != nil {
+			common.PrintError(err.Error())
+			os.Exit(1)
+		}
+	}
+	journal, err := openJournal(opts, NewRunID())
+	if err != nil {
+		common.PrintError(err.Error())
+		os.Exit(1)
+	}
+	defer journal.Close()
+	if err = journal.Start(opts); err != nil {
+		common.PrintError(err.Error())
+		os.Exit(1)
+	}
+	execute(opts, journal, NewCheckpoint(journal))
+}
+
+// Resume 继续执行中断的运行,已处理过的文件直接跳过
+func Resume(opts *Options, runID string) error {
+	journalPath, err := getJournalPath(opts)
+	if err != nil {
+		return err
+	}
+	if runID == "" {
+		if runID, err = FindInterruptedRun(journalPath); err != nil {
+			return err
+		}
+	}
+	journal, err := OpenJournal(journalPath, runID)
+	if err != nil {
+		return err
+	}
+	defer journal.Close()
+	checkpoint, runOpts, err := LoadCheckpoint(journalPath, journal)
+	if err != nil {
+		return err
+	}
+	runOpts.Yes = opts.Yes
+	runOpts.JournalPath = opts.JournalPath
+	color.New(color.FgBlue).Add(color.Bold).Printf("\n继续运行ID为%s的中断任务\n", runID)
+	Confirm(runOpts)
+	execute(runOpts, journal, checkpoint)
+	return nil
+}
+
+// execute 实际执行重命名并在日志中记录运行结果
+func execute(opts *Options, journal *Journal, checkpoint *Checkpoint) {
+	renamer, err := NewRenamer(opts, NewJournalOperator(NewDiskOperator(), journal))
+	if err != nil {
+		common.PrintError(err.Error())
+		os.Exit(1)
+	}
+	renamer.Checkpoint = checkpoint
+	err = Run(opts, renamer)
+	if err == nil {
+		err = journal.Finish()
+	}
+	color.New(color.FgBlue).Printf("\n本次运行ID: %s,如需撤销可执行: go-rename undo %s\n", journal.RunID, journal.RunID)
 	if err != nil {
+		color.New(color.FgBlue).Printf("处理中断,可执行 go-rename resume %s 继续处理\n", journal.RunID)
+		if errors.Is(err, context.Canceled) {
+			common.PrintError("已收到中断信号,当前文件处理完成后结束运行")
+			os.Exit(130)
+		}
 		common.PrintError(err.Error())
 		os.Exit(1)
 	}
+	color.New(color.FgGreen).Add(color.Bold).Println("\n=======================处理完成=======================")
+}
+
+// RunUndo 撤销指定运行ID的全部操作
+func RunUndo(opts *Options, runID string, force bool) error {
+	journalPath, err := getJournalPath(opts)
+	if err != nil {
+		return err
+	}
+	entries, err := ReadUndoEntries(journalPath, runID)
+	if err != nil {
+		return err
+	}
+	common.PrintDividingLine()
+	color.New(color.FgBlue).Add(color.Bold).Println("【撤销确认】")
+	color.New().Add(color.FgRed).Printf("运行ID%s共有%d条操作记录,将按倒序恢复为原文件名\n\n", runID, len(entries))
+	if !opts.Yes && !confirmYesNo() {
+		common.PrintError("结束运行")
+		os.Exit(1)
+	}
+	journal, err := openJournal(opts, NewRunID())
+	if err != nil {
+		return err
+	}
+	defer journal.Close()
+	ctx, stop := newInterruptContext()
+	defer stop()
+	result := Undo(ctx, entries, force, journal)
+	for _, skipped := range result.Skipped {
+		color.New(color.FgYellow).Println("未恢复 " + skipped)
+	}
+	if result.Remaining > 0 {
+		color.New(color.FgYellow).Printf("\n撤销中断,%d条记录未处理,可再次执行撤销\n", result.Remaining)
+	}
+	color.New(color.FgGreen).Add(color.Bold).Printf("\n共恢复%d个文件,%d个文件未恢复\n", result.Restored, len(result.Skipped))
+	color.New(color.FgBlue).Printf("本次撤销的运行ID: %s\n", journal.RunID)
+	return nil
+}
+
+// getJournalPath 获取日志文件路径,未指定时使用默认路径
+func getJournalPath(opts *Options) (string, error) {
+	if opts.JournalPath != "" {
+		return opts.JournalPath, nil
+	}
+	return DefaultJournalPath()
+}
+
+// openJournal 以指定运行ID打开日志文件
+func openJournal(opts *Options, runID string) (*Journal, error) {
+	journalPath, err := getJournalPath(opts)
+	if err != nil {
+		return nil, err
+	}
+	return OpenJournal(journalPath, runID)
+}
+
+// confirmYesNo 等待用户输入y/n
+func confirmYesNo() bool {
+	var confirmText string
+	for {
+		fmt.Print("确认处理吗? y是n否\n请输入y/n: ")
+		if _, err := fmt.Scanln(&confirmText); err != nil {
+			common.PrintError("输入错误，请输入y/n")
+			continue
+		}
+		switch confirmText {
+		case "Y", "y":
+			return true
+		case "N", "n":
+			return false
+		default:
+			common.PrintError("输入错误,请输入y/n")
+		}
+	}
+}
+
+// Preview 生成并打印重命名计划,不修改任何文件
+func Preview(opts *Options) error {
+	operator := NewPlanOperator()
+	renamer, err := NewRenamer(opts, operator)
+	if err != nil {
+		return err
+	}
+	if err = Run(opts, renamer); err != nil {
+		return err
+	}
+	PrintPlan(opts.Dir, operator.Items, renamer.Sources)
+	return nil
+}
+
+// Run 使用指定的重命名器执行重命名策略,收到中断信号时在当前文件处理完成后停止
+func Run(opts *Options, renamer *Renamer) error {
+	ctx, stop := newInterruptContext()
+	defer stop()
+	common.PrintDividingLine()
+	color.New(color.FgBlue).Add(color.Bold).Println("正在统计文件数量,请稍后...")
+	renameStrategy, err := NewRenameStrategy(opts, renamer)
+	if err != nil {
+		return err
+	}
+	fileCount, err := renameStrategy.CountFiles(ctx, opts.Dir)
+	if err != nil {
+		return err
+	}
 	color.New(color.FgBlue).Add(color.Bold).Println(fmt.Sprintf("共计%d个需处理的文件,开始进行处理\n", fileCount))
 	color.New().Add(color.Bold).Println("处理进度")
 	p := mpb.New(mpb.WithWidth(64))
@@ -218,13 +564,191 @@ func Execute() {
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		if err = renameStrategy.Rename(dir, bar); err != nil {
-			common.PrintError(err.Error())
-			os.Exit(1)
+		if err = renameStrategy.Rename(ctx, opts.Dir, bar); err != nil {
+			// 出错或中断时结束进度条,避免等待未完成的进度
+			bar.Abort(false)
 		}
 	}()
 	// 确保文件遍历完整
 	wg.Wait()
 	p.Wait()
-	color.New(color.FgGreen).Add(color.Bold).Println("\n=======================处理完成=======================")
+	renamer.Summary.Print()
+	return err
+}
+
+// newInterruptContext 创建收到SIGINT/SIGTERM时取消的上下文,
+// 取消后恢复信号的默认行为,再次中断将直接结束进程
+func newInterruptContext() (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+	return ctx, stop
+}
+
+// PrintPlan 打印重命名计划,sources为各文件拍摄时间的来源
+func PrintPlan(dir string, items []*PlanItem, sources map[string]string) {
+	common.PrintDividingLine()
+	color.New(color.FgBlue).Add(color.Bold).Println("【重命名计划】")
+	var unknownDateCount, replaceCount, copyCount, deleteCount int
+	for _, item := range items {
+		if item.Delete {
+			deleteCount++
+			fmt.Printf("%s ", relPath(dir, item.OldPath))
+			color.New(color.FgRed).Println("(删除)")
+			continue
+		}
+		action := "->"
+		if item.Replace {
+			action = "=>"
+			replaceCount++
+		}
+		if item.Copy {
+			action = "+>"
+			copyCount++
+		}
+		if filepath.Base(filepath.Dir(item.NewPath)) == UnknownDateDir {
+			unknownDateCount++
+		}
+		fmt.Printf("%s %s %s", relPath(dir, item.OldPath), action, relPath(dir, item.NewPath))
+		if source := sources[item.OldPath]; source != "" {
+			color.New(color.FgHiBlack).Printf(" [%s]", source)
+		}
+		fmt.Println()
+	}
+	fmt.Println()
+	if copyCount > 0 {
+		color.New(color.FgGreen).Add(color.Bold).Printf("共计%d个文件将被复制(+>),其中%d个复制到%s文件夹\n", copyCount, unknownDateCount, UnknownDateDir)
+	} else {
+		color.New(color.FgGreen).Add(color.Bold).Printf("共计%d个文件将被重命名或移动,其中%d个移至%s文件夹,%d个与已有文件合并(=>)\n", len(items)-deleteCount, unknownDateCount, UnknownDateDir, replaceCount)
+	}
+	if deleteCount > 0 {
+		color.New(color.FgGreen).Add(color.Bold).Printf("共计%d个附属文件将被删除\n", deleteCount)
+	}
+	color.New(color.FgGreen).Add(color.Bold).Println("预览完成,未修改任何文件")
+}
+
+// relPath 返回相对于处理目录的路径,无法计算时返回原路径
+func relPath(dir, path string) string {
+	// 不在处理的目录中的文件(如整理模式的目标目录)显示完整路径
+	if rel, err := filepath.Rel(dir, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return rel
+	}
+	return path
+}
+
+// exampleFileName 按文件名模板生成示例文件名
+func exampleFileName(opts *Options, fileName string) string {
+	template, err := ParseTemplate(opts.GetTemplate())
+	if err != nil {
+		return err.Error()
+	}
+	namer := NewNamer(template, opts.PrefixRules, opts.GetExtPolicy())
+	if opts.RenameType == RenameTypeOrganize || opts.RenameType == RenameTypeImport {
+		folder, err := ParseFolderTemplate(opts.GetFolderTemplate())
+		if err != nil {
+			return err.Error()
+		}
+		namer.Organize("", folder)
+	}
+	file := &mediaFile{path: fileName, cameraLoaded: true, cameraMake: "Canon", cameraModel: "Canon EOS R6"}
+	return namer.render(time.Date(2025, 6, 6, 12, 16, 1, 0, time.Local), file, 1, "")
+}
+
+// extCaseUsage 扩展名大小写处理方式的说明
+func extCaseUsage() string {
+	lines := make([]string, 0, len(ExtCaseTextMap))
+	for _, extCase := range ExtCases() {
+		lines = append(lines, fmt.Sprintf("%s: %s", extCase, ExtCaseTextMap[extCase]))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// dateSourceUsage 拍摄时间来源的说明
+func dateSourceUsage() string {
+	lines := make([]string, 0, len(DateSourceTextMap))
+	for _, source := range DateSources() {
+		lines = append(lines, fmt.Sprintf("%s: %s", source, DateSourceTextMap[source]))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// timeZoneUsage 时区处理方式的说明
+func timeZoneUsage() string {
+	names := funk.Keys(TimeZoneTextMap).([]string)
+	sort.Strings(names)
+	lines := make([]string, 0, len(names))
+	for _, name := range names {
+		lines = append(lines, fmt.Sprintf("%s: %s", name, TimeZoneTextMap[name]))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// takeoutJSONUsage Takeout JSON附属文件处理方式的说明
+func takeoutJSONUsage() string {
+	lines := make([]string, 0, len(TakeoutJSONTextMap))
+	for _, action := range TakeoutJSONActions() {
+		lines = append(lines, fmt.Sprintf("%s: %s", action, TakeoutJSONTextMap[action]))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// metadataBackendUsage 元数据读取方式的说明
+func metadataBackendUsage() string {
+	lines := make([]string, 0, len(MetadataBackendTextMap))
+	for _, backend := range MetadataBackends() {
+		lines = append(lines, fmt.Sprintf("%s: %s", backend, MetadataBackendTextMap[backend]))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// mediaKindUsage 媒体类型的说明
+func mediaKindUsage() string {
+	lines := make([]string, 0, len(MediaKindTextMap))
+	for _, kind := range MediaKinds() {
+		lines = append(lines, fmt.Sprintf("%s: %s", kind, MediaKindTextMap[kind]))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// templateTokenUsage 模板变量的说明
+func templateTokenUsage() string {
+	lines := make([]string, 0, len(TemplateTokenTextMap))
+	for _, token := range TemplateTokens() {
+		lines = append(lines, fmt.Sprintf("%s: %s", token, TemplateTokenTextMap[strings.Trim(token, "{}")]))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// CheckImportTarget 检查导入的图库目录,不能与处理的目录相同或位于其中
+func CheckImportTarget(dir, target string) error {
+	if target == "" {
+		return fmt.Errorf("请通过--target指定图库目录")
+	}
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	absTarget, err := filepath.Abs(target)
+	if err != nil {
+		return err
+	}
+	if rel, err := filepath.Rel(absDir, absTarget); err == nil && !strings.HasPrefix(rel, "..") {
+		return fmt.Errorf("图库目录不能与处理的目录相同或位于其中")
+	}
+	return nil
+}
+
+// CheckDir 检查目录是否存在
+func CheckDir(dir string) error {
+	info, err := os.Stat(dir)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("目录不存在")
+	} else if err != nil {
+		return err
+	} else if !info.IsDir() {
+		return fmt.Errorf("请输入正确的目录路径")
+	}
+	return nil
 }
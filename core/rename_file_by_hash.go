@@ -1,6 +1,7 @@
 package core
 
 import (
+	"context"
 	"fmt"
 	"github.com/vbauerster/mpb/v8"
 	"os"
@@ -9,22 +10,27 @@ import (
 
 // RenameFileByHash 根据文件hash重命名图片/视频文件
 type RenameFileByHash struct {
+	*Renamer
 }
 
-func NewRenameFileByHash() *RenameFileByHash {
-	return &RenameFileByHash{}
+func NewRenameFileByHash(renamer *Renamer) *RenameFileByHash {
+	return &RenameFileByHash{Renamer: renamer}
 }
 
 // CountFiles 统计需要重命名的文件数量
-func (r *RenameFileByHash) CountFiles(dir string) (int64, error) {
+func (r *RenameFileByHash) CountFiles(ctx context.Context, dir string) (int64, error) {
 	var fileCount int64 = 0
 	// 遍历目录及其子目录
 	if err := filepath.Walk(dir, func(path string, file os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
+		// 收到中断信号后不再处理新文件
+		if err = ctx.Err(); err != nil {
+			return err
+		}
 		// 只处理图片和视频，过滤掉隐藏文件
-		if (!IsImage(path) && !IsVideo(path)) || file.IsDir() || IsHiddenFile(file.Name()) {
+		if (!IsImage(path) && !IsVideo(path)) || file.IsDir() || IsHiddenFile(file.Name()) || r.Checkpoint.IsProcessed(path) {
 			return nil
 		}
 		fileCount++
@@ -36,27 +42,36 @@ func (r *RenameFileByHash) CountFiles(dir string) (int64, error) {
 }
 
 // Rename 重命名
-func (r *RenameFileByHash) Rename(dir string, bar *mpb.Bar) error {
-	// 遍历目录及其子目录
-	return filepath.Walk(dir, func(path string, file os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
+func (r *RenameFileByHash) Rename(ctx context.Context, dir string, bar *mpb.Bar) error {
+	// 遍历目录及其子目录,先列出全部文件,重命名后的文件不会被再次处理
+	return walkDir(ctx, dir, func(path string, file os.FileInfo) error {
 		// 只处理图片和视频，过滤掉隐藏文件
-		if (!IsImage(path) && !IsVideo(path)) || file.IsDir() || IsHiddenFile(file.Name()) {
-			return nil
-		}
-		newFileName, renameErr := GetNameByFileHash(path, file)
-		if renameErr != nil {
-			fmt.Printf("Error renaming %s: %v\n", path, renameErr)
+		if (!IsImage(path) && !IsVideo(path)) || IsHiddenFile(file.Name()) || r.Checkpoint.IsProcessed(path) {
 			return nil
 		}
-		// 重命名文件
-		err = os.Rename(path, newFileName)
-		if err != nil {
-			fmt.Printf("Error renaming %s to %s: %v\n", path, newFileName, err)
-		}
-		bar.Increment()
-		return nil
+		// 当前文件不受中断影响,处理完成后再退出
+		r.RenameSingleFileByHash(context.WithoutCancel(ctx), path, file)
+		return r.complete(path, bar)
+	}, func() error {
+		return r.flush(bar)
 	})
 }
+
+// RenameSingleFileByHash 以md5重命名单个文件
+func (r *Renamer) RenameSingleFileByHash(ctx context.Context, path string, file os.FileInfo) {
+	newFileName, err := GetNameByFileHash(ctx, path, file, r.Namer.ExtPolicy)
+	if err != nil {
+		fmt.Printf("Error renaming %s: %v\n", path, err)
+		r.Summary.AddFailure(path, err)
+		return
+	}
+	// 重命名文件,md5相同的文件直接覆盖
+	if err = r.Operator.Replace(path, newFileName); err != nil {
+		fmt.Printf("Error renaming %s to %s: %v\n", path, newFileName, err)
+		r.Summary.AddFailure(path, err)
+		return
+	}
+	if newFileName != path {
+		r.Summary.Moved++
+	}
+}
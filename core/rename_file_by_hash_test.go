@@ -0,0 +1,53 @@
+package core
+
+import (
+	"context"
+	"crypto/md5"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vbauerster/mpb/v8"
+)
+
+func TestRenameFileByHash(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	// a.jpg与b.jpg内容相同,重命名后只保留一个
+	files := map[string]string{"a.jpg": "photo", "b.jpg": "photo", "sub/c.mp4": "video"}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	renamer, err := NewRenamer(&Options{RenameType: RenameTypeFileByHash}, NewDiskOperator())
+	if err != nil {
+		t.Fatalf("NewRenamer() error = %v", err)
+	}
+	bar := mpb.New(mpb.WithOutput(io.Discard)).AddBar(int64(len(files)))
+	if err = NewRenameFileByHash(renamer).Rename(context.Background(), dir, bar); err != nil {
+		t.Fatalf("Rename() error = %v", err)
+	}
+	// 重命名后的文件不应再次处理
+	if renamer.Summary.Processed != 3 || renamer.Summary.Moved != 3 {
+		t.Errorf("Processed = %d, Moved = %d, want 3 and 3", renamer.Summary.Processed, renamer.Summary.Moved)
+	}
+	for sub, data := range map[string]string{".": "photo", "sub": "video"} {
+		var names []string
+		entries, _ := os.ReadDir(filepath.Join(dir, sub))
+		for _, entry := range entries {
+			if !entry.IsDir() {
+				names = append(names, entry.Name())
+			}
+		}
+		want := fmt.Sprintf("%x", md5.Sum([]byte(data)))
+		if len(names) != 1 || strings.TrimSuffix(names[0], filepath.Ext(names[0])) != want {
+			t.Errorf("files in %s = %v, want one file named %s", sub, names, want)
+		}
+	}
+}
@@ -1,7 +1,7 @@
 package core
 
 import (
-	"fmt"
+	"context"
 	"github.com/vbauerster/mpb/v8"
 	"os"
 	"os/exec"
@@ -11,26 +11,30 @@ import (
 
 // RenameImage 根据拍摄时间重命名图片文件
 type RenameImage struct {
-	MatchFailureHandlerType int // 匹配失败的处理方式
+	*Renamer
 }
 
-func NewRenameImage(matchFailureHandlerType int) *RenameImage {
-	return &RenameImage{MatchFailureHandlerType: matchFailureHandlerType}
+func NewRenameImage(renamer *Renamer) *RenameImage {
+	return &RenameImage{Renamer: renamer}
 }
 
 // CountFiles 统计需要重命名的文件数量
-func (r *RenameImage) CountFiles(dir string) (int64, error) {
+func (r *RenameImage) CountFiles(ctx context.Context, dir string) (int64, error) {
 	var fileCount int64 = 0
 	// 遍历目录及其子目录
 	if err := filepath.Walk(dir, func(path string, file os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
+		// 收到中断信号后不再处理新文件
+		if err = ctx.Err(); err != nil {
+			return err
+		}
 		if filepath.Dir(path) == filepath.Join(dir, UnknownDateDir) {
 			return nil
 		}
 		// 只处理图片类型文件，过滤掉隐藏文件
-		if !IsImage(path) || file.IsDir() || IsHiddenFile(file.Name()) {
+		if !IsImage(path) || file.IsDir() || IsHiddenFile(file.Name()) || r.Checkpoint.IsProcessed(path) {
 			return nil
 		}
 		fileCount++
@@ -41,71 +45,33 @@ func (r *RenameImage) CountFiles(dir string) (int64, error) {
 	return fileCount, nil
 }
 
-// Rename 重命名
-func (r *RenameImage) Rename(dir string, bar *mpb.Bar) error {
+// Rename 重命名,同一目录下的文件统一按拍摄时间顺序移动
+func (r *RenameImage) Rename(ctx context.Context, dir string, bar *mpb.Bar) error {
 	// 遍历目录及其子目录
-	return filepath.Walk(dir, func(path string, file os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
+	return walkDir(ctx, dir, func(path string, file os.FileInfo) error {
 		if filepath.Dir(path) == filepath.Join(dir, UnknownDateDir) {
 			return nil
 		}
 		// 只处理图片类型文件，过滤掉隐藏文件
-		if !IsImage(path) || file.IsDir() || IsHiddenFile(file.Name()) {
+		if !IsImage(path) || IsHiddenFile(file.Name()) || r.Checkpoint.IsProcessed(path) {
 			return nil
 		}
-		if err = RenameSingleImage(path, file, r.MatchFailureHandlerType); err != nil {
+		// 当前文件不受中断影响,处理完成后再退出
+		if err := r.RenameSingleImage(context.WithoutCancel(ctx), path, file); err != nil {
 			return err
 		}
-		bar.Increment()
-		return nil
+		return r.complete(path, bar)
+	}, func() error {
+		return r.flush(bar)
 	})
 }
 
 // RenameSingleImage 重命名单张图片
-func RenameSingleImage(path string, file os.FileInfo, matchFailureHandlerType int) error {
+func (r *Renamer) RenameSingleImage(ctx context.Context, path string, file os.FileInfo) error {
 	if !IsImage(path) {
 		return nil
 	}
-	originalTime, err := GetOriginalTime(path)
-	if err != nil {
-		return fmt.Errorf("重命名%s文件时错误:%v\n", path, err)
-	}
-	// 没有EXIF拍摄日期
-	if originalTime == "" {
-		switch matchFailureHandlerType {
-		case MatchFailureHandlerTypeIgnore:
-			return nil
-		case MatchFailureHandlerTypeMoveToUnknownDateDir:
-			// 没有EXIF的文件移至unknown-date文件夹
-			targetDir := filepath.Join(filepath.Dir(path), UnknownDateDir)
-			// 检查目标目录是否存在
-			if _, err := os.Stat(targetDir); os.IsNotExist(err) {
-				if err = os.Mkdir(targetDir, 0755); err != nil {
-					return err
-				}
-			}
-			// 构建目标文件的完整路径
-			targetPath := filepath.Join(targetDir, filepath.Base(path))
-			// 移动文件
-			targetPath, err = RenameWithConflictResolution(path, targetPath)
-			if err != nil {
-				fmt.Printf("Error move %s to %s: %v\n", path, targetPath, err)
-			}
-			return nil
-		case MatchFailureHandlerTypeUseFileCreationTime:
-			// 没有EXIF的按文件创建时间命名
-			creationTime, _ := GetFileCreationTime(path)
-			originalTime = creationTime
-		}
-	}
-	newFilePath := filepath.Join(filepath.Dir(path), GetDateFileName(originalTime, file.Name()))
-	// 重命名文件
-	if newFilePath, err = RenameWithConflictResolution(path, newFilePath); err != nil {
-		fmt.Printf("Error renaming %s to %s: %v\n", path, newFilePath, err)
-	}
-	return nil
+	return r.renameByCaptureTime(ctx, path)
 }
 
 // CommandExists 判断命令是否存在
@@ -1,6 +1,7 @@
 package core
 
 import (
+	"context"
 	"github.com/vbauerster/mpb/v8"
 	"os"
 	"path/filepath"
@@ -8,29 +9,30 @@ import (
 
 // RenameImageAndVideo 根据拍摄时间重命名图片/视频文件
 type RenameImageAndVideo struct {
-	MatchFailureHandlerType int // 匹配失败的处理方式
+	*Renamer
 }
 
-func NewRenameImageAndVideo(matchFailureHandlerType int) *RenameImageAndVideo {
-	return &RenameImageAndVideo{MatchFailureHandlerType: matchFailureHandlerType}
+func NewRenameImageAndVideo(renamer *Renamer) *RenameImageAndVideo {
+	return &RenameImageAndVideo{Renamer: renamer}
 }
 
 // CountFiles 统计需要重命名的文件数量
-func (r *RenameImageAndVideo) CountFiles(dir string) (int64, error) {
+func (r *RenameImageAndVideo) CountFiles(ctx context.Context, dir string) (int64, error) {
 	var fileCount int64 = 0
-	if err := CheckMediainfoCommandExists(); err != nil {
-		return 0, err
-	}
 	// 遍历目录及其子目录
 	if err := filepath.Walk(dir, func(path string, file os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
+		// 收到中断信号后不再处理新文件
+		if err = ctx.Err(); err != nil {
+			return err
+		}
 		if filepath.Dir(path) == filepath.Join(dir, UnknownDateDir) {
 			return nil
 		}
 		// 只处理图片和视频类型文件，过滤掉隐藏文件
-		if (!IsImage(path) && !IsVideo(path)) || file.IsDir() || IsHiddenFile(file.Name()) {
+		if (!IsImage(path) && !IsVideo(path)) || file.IsDir() || IsHiddenFile(file.Name()) || r.Checkpoint.IsProcessed(path) {
 			return nil
 		}
 		fileCount++
@@ -41,38 +43,34 @@ func (r *RenameImageAndVideo) CountFiles(dir string) (int64, error) {
 	return fileCount, nil
 }
 
-// Rename 重命名
-func (r *RenameImageAndVideo) Rename(dir string, bar *mpb.Bar) error {
+// Rename 重命名,同一目录下的文件统一按拍摄时间顺序移动
+func (r *RenameImageAndVideo) Rename(ctx context.Context, dir string, bar *mpb.Bar) error {
 	// 遍历目录及其子目录
-	if err := filepath.Walk(dir, func(path string, file os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
+	return walkDir(ctx, dir, func(path string, file os.FileInfo) error {
 		if filepath.Dir(path) == filepath.Join(dir, UnknownDateDir) {
 			return nil
 		}
-		// 过滤掉隐藏文件
-		if file.IsDir() || IsHiddenFile(file.Name()) {
+		// 只处理图片和视频类型文件，过滤掉隐藏文件
+		if (!IsImage(path) && !IsVideo(path)) || IsHiddenFile(file.Name()) || r.Checkpoint.IsProcessed(path) {
 			return nil
 		}
-		if err = RenameSingleImageOrVideo(path, file, r.MatchFailureHandlerType); err != nil {
+		// 当前文件不受中断影响,处理完成后再退出
+		if err := r.RenameSingleImageOrVideo(context.WithoutCancel(ctx), path, file); err != nil {
 			return err
 		}
-		bar.Increment()
-		return nil
-	}); err != nil {
-		return err
-	}
-	return nil
+		return r.complete(path, bar)
+	}, func() error {
+		return r.flush(bar)
+	})
 }
 
 // RenameSingleImageOrVideo 重命名单个图片/视频文件
-func RenameSingleImageOrVideo(path string, file os.FileInfo, matchFailureHandlerType int) error {
+func (r *Renamer) RenameSingleImageOrVideo(ctx context.Context, path string, file os.FileInfo) error {
 	if IsImage(path) {
-		return RenameSingleImage(path, file, matchFailureHandlerType)
+		return r.RenameSingleImage(ctx, path, file)
 	}
 	if IsVideo(path) {
-		return RenameSingleVideo(path, file, matchFailureHandlerType)
+		return r.RenameSingleVideo(ctx, path, file)
 	}
 	return nil
 }
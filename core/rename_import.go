@@ -0,0 +1,59 @@
+package core
+
+import (
+	"context"
+	"github.com/vbauerster/mpb/v8"
+	"os"
+	"path/filepath"
+)
+
+// RenameImport 将图片/视频文件复制到图库目录中按日期分层的文件夹,原文件默认保留
+type RenameImport struct {
+	*Renamer
+}
+
+func NewRenameImport(renamer *Renamer) *RenameImport {
+	return &RenameImport{Renamer: renamer}
+}
+
+// CountFiles 统计需要导入的文件数量
+func (r *RenameImport) CountFiles(ctx context.Context, dir string) (int64, error) {
+	var fileCount int64 = 0
+	// 遍历目录及其子目录
+	if err := filepath.Walk(dir, func(path string, file os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		// 收到中断信号后不再处理新文件
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+		// 只处理图片和视频类型文件，过滤掉隐藏文件
+		if (!IsImage(path) && !IsVideo(path)) || file.IsDir() || IsHiddenFile(file.Name()) || r.Checkpoint.IsProcessed(path) {
+			return nil
+		}
+		fileCount++
+		return nil
+	}); err != nil {
+		return 0, err
+	}
+	return fileCount, nil
+}
+
+// Rename 导入,同一目录下的文件统一按拍摄时间顺序复制
+func (r *RenameImport) Rename(ctx context.Context, dir string, bar *mpb.Bar) error {
+	// 遍历目录及其子目录
+	return walkDir(ctx, dir, func(path string, file os.FileInfo) error {
+		// 只处理图片和视频类型文件，过滤掉隐藏文件,图库目录不在处理的目录中,无需跳过unknown-date文件夹
+		if (!IsImage(path) && !IsVideo(path)) || IsHiddenFile(file.Name()) || r.Checkpoint.IsProcessed(path) {
+			return nil
+		}
+		// 当前文件不受中断影响,处理完成后再退出
+		if err := r.RenameSingleImageOrVideo(context.WithoutCancel(ctx), path, file); err != nil {
+			return err
+		}
+		return r.complete(path, bar)
+	}, func() error {
+		return r.flush(bar)
+	})
+}
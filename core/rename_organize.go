@@ -0,0 +1,95 @@
+package core
+
+import (
+	"context"
+	"github.com/vbauerster/mpb/v8"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// RenameOrganize 根据拍摄时间重命名图片/视频文件,并移至目标目录中按日期分层的文件夹
+type RenameOrganize struct {
+	*Renamer
+}
+
+func NewRenameOrganize(renamer *Renamer) *RenameOrganize {
+	return &RenameOrganize{Renamer: renamer}
+}
+
+// CountFiles 统计需要整理的文件数量
+func (r *RenameOrganize) CountFiles(ctx context.Context, dir string) (int64, error) {
+	var fileCount int64 = 0
+	organized := r.isOrganized(dir)
+	// 遍历目录及其子目录
+	if err := filepath.Walk(dir, func(path string, file os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		// 收到中断信号后不再处理新文件
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+		if organized(path) {
+			if file.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		// 只处理图片和视频类型文件，过滤掉隐藏文件
+		if (!IsImage(path) && !IsVideo(path)) || file.IsDir() || IsHiddenFile(file.Name()) || r.Checkpoint.IsProcessed(path) {
+			return nil
+		}
+		fileCount++
+		return nil
+	}); err != nil {
+		return 0, err
+	}
+	return fileCount, nil
+}
+
+// Rename 重命名并整理,同一目录下的文件统一按拍摄时间顺序移动
+func (r *RenameOrganize) Rename(ctx context.Context, dir string, bar *mpb.Bar) error {
+	organized := r.isOrganized(dir)
+	// 遍历目录及其子目录
+	return walkDir(ctx, dir, func(path string, file os.FileInfo) error {
+		if organized(path) {
+			return nil
+		}
+		// 只处理图片和视频类型文件，过滤掉隐藏文件
+		if (!IsImage(path) && !IsVideo(path)) || IsHiddenFile(file.Name()) || r.Checkpoint.IsProcessed(path) {
+			return nil
+		}
+		// 当前文件不受中断影响,处理完成后再退出
+		if err := r.RenameSingleImageOrVideo(context.WithoutCancel(ctx), path, file); err != nil {
+			return err
+		}
+		return r.complete(path, bar)
+	}, func() error {
+		return r.flush(bar)
+	})
+}
+
+// isOrganized 返回判断文件是否已整理过的函数:目标目录为处理目录的子目录时目标目录中的文件均已整理过,否则只有目标目录的unknown-date文件夹中的文件已整理过
+func (r *RenameOrganize) isOrganized(dir string) func(path string) bool {
+	unknownDateDir := filepath.Join(r.Namer.Target, UnknownDateDir)
+	absDir, dirErr := filepath.Abs(dir)
+	absTarget, targetErr := filepath.Abs(r.Namer.Target)
+	skipTarget := dirErr == nil && targetErr == nil && absTarget != absDir && isInDir(absDir, absTarget)
+	return func(path string) bool {
+		if filepath.Dir(path) == unknownDateDir {
+			return true
+		}
+		if !skipTarget {
+			return false
+		}
+		absPath, err := filepath.Abs(path)
+		return err == nil && isInDir(absTarget, absPath)
+	}
+}
+
+// isInDir 判断路径是否为目录本身或位于其中
+func isInDir(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
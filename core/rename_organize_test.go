@@ -0,0 +1,91 @@
+package core
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/vbauerster/mpb/v8"
+)
+
+func TestRenameOrganizeTargetInsideDir(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string // 相对于处理目录的目标目录,为空时使用处理目录
+	}{
+		{"目标目录为处理目录", ""},
+		{"目标目录为处理目录的子目录", "library"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			// library已存在且排在import之后,移入其中的文件不应再次处理
+			for _, sub := range []string{"import", "library", "zz"} {
+				if err := os.Mkdir(filepath.Join(dir, sub), 0755); err != nil {
+					t.Fatal(err)
+				}
+			}
+			writeTakeoutFiles(t, filepath.Join(dir, "import"), "a.jpg", "b.mp4")
+			target := dir
+			if tt.target != "" {
+				target = filepath.Join(dir, tt.target)
+			}
+			renamer, err := NewRenamer(&Options{
+				RenameType:              RenameTypeOrganize,
+				Target:                  target,
+				FolderTemplate:          "zz/{YYYY}",
+				MatchFailureHandlerType: MatchFailureHandlerTypeIgnore,
+				DateSources:             []string{DateSourceModTime},
+			}, NewDiskOperator())
+			if err != nil {
+				t.Fatalf("NewRenamer() error = %v", err)
+			}
+			bar := mpb.New(mpb.WithOutput(io.Discard)).AddBar(2)
+			if err = NewRenameOrganize(renamer).Rename(context.Background(), dir, bar); err != nil {
+				t.Fatalf("Rename() error = %v", err)
+			}
+			if renamer.Summary.Processed != 2 || renamer.Summary.Moved != 2 {
+				t.Errorf("Processed = %d, Moved = %d, want 2 and 2", renamer.Summary.Processed, renamer.Summary.Moved)
+			}
+		})
+	}
+}
+
+func TestRenameOrganizeSkipsTarget(t *testing.T) {
+	dir := t.TempDir()
+	// 目标目录位于处理目录中,其中已整理的文件不应统计或再次处理
+	target := filepath.Join(dir, "library")
+	for _, sub := range []string{"import", filepath.Join("library", "2020")} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeTakeoutFiles(t, filepath.Join(dir, "import"), "a.jpg")
+	writeTakeoutFiles(t, filepath.Join(target, "2020"), "IMG_20200101_120000.JPG")
+	renamer, err := NewRenamer(&Options{
+		RenameType:              RenameTypeOrganize,
+		Target:                  target,
+		FolderTemplate:          "{YYYY}",
+		MatchFailureHandlerType: MatchFailureHandlerTypeIgnore,
+		DateSources:             []string{DateSourceModTime},
+	}, NewDiskOperator())
+	if err != nil {
+		t.Fatalf("NewRenamer() error = %v", err)
+	}
+	organize := NewRenameOrganize(renamer)
+	if count, err := organize.CountFiles(context.Background(), dir); err != nil || count != 1 {
+		t.Fatalf("CountFiles() = %d, %v, want 1", count, err)
+	}
+	bar := mpb.New(mpb.WithOutput(io.Discard)).AddBar(1)
+	if err = organize.Rename(context.Background(), dir, bar); err != nil {
+		t.Fatalf("Rename() error = %v", err)
+	}
+	if renamer.Summary.Processed != 1 || renamer.Summary.Moved != 1 {
+		t.Errorf("Processed = %d, Moved = %d, want 1 and 1", renamer.Summary.Processed, renamer.Summary.Moved)
+	}
+	if _, err = os.Stat(filepath.Join(target, "2020", "IMG_20200101_120000.JPG")); err != nil {
+		t.Errorf("organized file was moved: %v", err)
+	}
+}
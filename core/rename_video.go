@@ -1,39 +1,39 @@
 package core
 
 import (
-	"fmt"
+	"context"
 	"os"
 	"path/filepath"
-	"runtime"
 
 	"github.com/vbauerster/mpb/v8"
 )
 
 // RenameVideo 根据拍摄时间重命名视频文件
 type RenameVideo struct {
-	MatchFailureHandlerType int // 匹配失败的处理方式
+	*Renamer
 }
 
-func NewRenameVideo(matchFailureHandlerType int) *RenameVideo {
-	return &RenameVideo{MatchFailureHandlerType: matchFailureHandlerType}
+func NewRenameVideo(renamer *Renamer) *RenameVideo {
+	return &RenameVideo{Renamer: renamer}
 }
 
 // CountFiles 统计需要重命名的文件数量
-func (r *RenameVideo) CountFiles(dir string) (int64, error) {
+func (r *RenameVideo) CountFiles(ctx context.Context, dir string) (int64, error) {
 	var fileCount int64 = 0
-	if err := CheckMediainfoCommandExists(); err != nil {
-		return 0, err
-	}
 	// 遍历目录及其子目录
 	if err := filepath.Walk(dir, func(path string, file os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
+		// 收到中断信号后不再处理新文件
+		if err = ctx.Err(); err != nil {
+			return err
+		}
 		if filepath.Dir(path) == filepath.Join(dir, UnknownDateDir) {
 			return nil
 		}
 		// 只处理视频类型文件，过滤掉隐藏文件
-		if !IsVideo(path) || file.IsDir() || IsHiddenFile(file.Name()) {
+		if !IsVideo(path) || file.IsDir() || IsHiddenFile(file.Name()) || r.Checkpoint.IsProcessed(path) {
 			return nil
 		}
 		fileCount++
@@ -44,84 +44,31 @@ func (r *RenameVideo) CountFiles(dir string) (int64, error) {
 	return fileCount, nil
 }
 
-// Rename 重命名
-func (r *RenameVideo) Rename(dir string, bar *mpb.Bar) error {
+// Rename 重命名,同一目录下的文件统一按拍摄时间顺序移动
+func (r *RenameVideo) Rename(ctx context.Context, dir string, bar *mpb.Bar) error {
 	// 遍历目录及其子目录
-	if err := filepath.Walk(dir, func(path string, file os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
+	return walkDir(ctx, dir, func(path string, file os.FileInfo) error {
 		if filepath.Dir(path) == filepath.Join(dir, UnknownDateDir) {
 			return nil
 		}
 		// 只处理视频类型文件，过滤掉隐藏文件
-		if !IsVideo(path) || file.IsDir() || IsHiddenFile(file.Name()) {
+		if !IsVideo(path) || IsHiddenFile(file.Name()) || r.Checkpoint.IsProcessed(path) {
 			return nil
 		}
-		if err = RenameSingleVideo(path, file, r.MatchFailureHandlerType); err != nil {
+		// 当前文件不受中断影响,处理完成后再退出
+		if err := r.RenameSingleVideo(context.WithoutCancel(ctx), path, file); err != nil {
 			return err
 		}
-		bar.Increment()
-		return nil
-	}); err != nil {
-		return err
-	}
-	return nil
+		return r.complete(path, bar)
+	}, func() error {
+		return r.flush(bar)
+	})
 }
 
 // RenameSingleVideo 重命名单个视频
-func RenameSingleVideo(path string, file os.FileInfo, matchFailureHandlerType int) error {
+func (r *Renamer) RenameSingleVideo(ctx context.Context, path string, file os.FileInfo) error {
 	if !IsVideo(path) {
 		return nil
 	}
-	originalTime, err := GetVideoDate(path)
-	if err != nil {
-		return fmt.Errorf("重命名%s文件时错误:\n%v", path, err)
-	}
-	// 没有视频拍摄日期
-	if originalTime == "" {
-		switch matchFailureHandlerType {
-		case MatchFailureHandlerTypeIgnore:
-			return nil
-		case MatchFailureHandlerTypeMoveToUnknownDateDir:
-			// 没有拍摄日期的视频移至unknown-date文件夹
-			targetDir := filepath.Join(filepath.Dir(path), UnknownDateDir)
-			// 检查目标目录是否存在
-			if _, err := os.Stat(targetDir); os.IsNotExist(err) {
-				if err = os.Mkdir(targetDir, 0755); err != nil {
-					return err
-				}
-			}
-			// 构建目标文件的完整路径
-			targetPath := filepath.Join(targetDir, filepath.Base(path))
-			// 移动文件
-			targetPath, err = RenameWithConflictResolution(path, targetPath)
-			if err != nil {
-				fmt.Printf("Error move %s to %s: %v\n", path, targetPath, err)
-			}
-			return nil
-		case MatchFailureHandlerTypeUseFileCreationTime:
-			// 没有拍摄时间的按文件创建时间命名
-			creationTime, _ := GetFileCreationTime(path)
-			originalTime = creationTime
-		}
-	}
-	newFilePath := filepath.Join(filepath.Dir(path), GetDateFileName(originalTime, file.Name()))
-	// 重命名文件
-	if newFilePath, err = RenameWithConflictResolution(path, newFilePath); err != nil {
-		fmt.Printf("Error renaming %s to %s: %v\n", path, newFilePath, err)
-	}
-	return nil
-}
-
-// CheckMediainfoCommandExists 检查mediainfo命令是否存在
-func CheckMediainfoCommandExists() error {
-	if CommandExists("mediainfo") {
-		return nil
-	}
-	tips := "执行失败:获取视频拍摄时间需要安装mediainfo,请先安装"
-	if runtime.GOOS == "windows" {
-		return fmt.Errorf("%s\n下载链接:%s", tips, "https://mediaarea.net/download/binary/mediainfo-gui/25.04/MediaInfo_GUI_25.04_Windows.exe")
-	}
-	return fmt.Errorf("%s\n下载链接:%s", tips, "https://mediaarea.net/en/MediaInfo/Download")
+	return r.renameByCaptureTime(ctx, path)
 }
@@ -0,0 +1,378 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/vbauerster/mpb/v8"
+)
+
+// Renamer 重命名器,保存各策略共用的处理方式与文件操作器
+type Renamer struct {
+	MatchFailureHandlerType int               // 匹配失败的处理方式
+	Namer                   *Namer            // 文件命名器
+	Metadata                *MetadataReader   // 元数据读取器
+	DateChain               *DateChain        // 拍摄时间来源链
+	TimeZone                *TimeZonePolicy   // 时区处理方式
+	Sources                 map[string]string // 各文件拍摄时间的来源,用于预览
+	Operator                FileOperator      // 文件操作器
+	Checkpoint              *Checkpoint       // 运行检查点,预览时为nil
+	Summary                 *Summary          // 运行结果统计
+	Copy                    bool              // 复制文件而不是移动,原文件保留
+	DeleteSource            bool              // 复制并校验通过后删除原文件
+	TakeoutJSON             string            // Takeout JSON附属文件的处理方式
+	pending                 map[string]*pendingMove
+}
+
+// pendingMove 等待移动的文件,同一目录下的文件按拍摄时间顺序移动,使重名后缀与拍摄顺序一致
+type pendingMove struct {
+	oldPath string
+	newPath string
+	date    time.Time // 拍摄时间,没有拍摄时间时为零值
+	source  string    // 拍摄时间的来源
+}
+
+func NewRenamer(opts *Options, operator FileOperator) (*Renamer, error) {
+	template, err := ParseTemplate(opts.GetTemplate())
+	if err != nil {
+		return nil, err
+	}
+	metadata, err := opts.GetMetadataReader()
+	if err != nil {
+		return nil, err
+	}
+	timeZone, err := ParseTimeZonePolicy(opts.TimeZone)
+	if err != nil {
+		return nil, err
+	}
+	namer := NewNamer(template, opts.PrefixRules, opts.GetExtPolicy())
+	if opts.RenameType == RenameTypeOrganize || opts.RenameType == RenameTypeImport {
+		folder, err := ParseFolderTemplate(opts.GetFolderTemplate())
+		if err != nil {
+			return nil, err
+		}
+		namer.Organize(opts.GetTarget(), folder)
+	}
+	return &Renamer{
+		MatchFailureHandlerType: opts.MatchFailureHandlerType,
+		Namer:                   namer,
+		Metadata:                metadata,
+		DateChain:               NewDateChain(opts.GetDateSources(), FilenameRules(opts.FilenameRules)),
+		TimeZone:                timeZone,
+		Sources:                 make(map[string]string),
+		Operator:                operator,
+		Summary:                 NewSummary(),
+		Copy:                    opts.RenameType == RenameTypeImport,
+		DeleteSource:            opts.DeleteSource,
+		TakeoutJSON:             opts.GetTakeoutJSON(),
+		pending:                 make(map[string]*pendingMove),
+	}, nil
+}
+
+// move 移动文件(导入时为复制),目标已存在时添加_N后缀,并记录处理结果
+func (r *Renamer) move(oldPath, newPath string) {
+	if r.Copy {
+		r.copy(oldPath, newPath)
+		return
+	}
+	targetPath, err := r.Operator.Move(oldPath, newPath)
+	if err != nil {
+		fmt.Printf("Error renaming %s to %s: %v\n", oldPath, targetPath, err)
+		r.Summary.AddFailure(oldPath, err)
+		return
+	}
+	if targetPath != oldPath {
+		r.Summary.Moved++
+		r.moveSidecar(oldPath, targetPath)
+		r.moveTakeoutJSON(oldPath, targetPath)
+	}
+}
+
+// copy 复制文件并校验,目标已存在时添加_N后缀,并记录处理结果
+func (r *Renamer) copy(oldPath, newPath string) {
+	targetPath, err := r.Operator.Copy(oldPath, newPath, r.DeleteSource)
+	if err != nil {
+		fmt.Printf("Error copying %s to %s: %v\n", oldPath, targetPath, err)
+		r.Summary.AddFailure(oldPath, err)
+		return
+	}
+	if targetPath != oldPath {
+		r.Summary.Copied++
+		r.moveSidecar(oldPath, targetPath)
+		r.moveTakeoutJSON(oldPath, targetPath)
+	}
+}
+
+// moveSidecar 将XMP附属文件随原文件移动或复制,保持photo.jpg.xmp或photo.xmp的命名方式
+// 同名的其他文件(如RAW+JPG)共用photo.xmp时保留附属文件,由最后处理的文件带走
+func (r *Renamer) moveSidecar(oldPath, targetPath string) {
+	sidecar := FindSidecar(oldPath)
+	if sidecar == "" {
+		return
+	}
+	ext := filepath.Ext(sidecar)
+	newSidecar := targetPath + ext
+	if sidecar != oldPath+ext {
+		if hasSiblingFile(oldPath, sidecar) {
+			return
+		}
+		newSidecar = strings.TrimSuffix(targetPath, filepath.Ext(targetPath)) + ext
+	}
+	var err error
+	if r.Copy {
+		newSidecar, err = r.Operator.Copy(sidecar, newSidecar, r.DeleteSource)
+	} else {
+		newSidecar, err = r.Operator.Move(sidecar, newSidecar)
+	}
+	if err != nil {
+		fmt.Printf("Error moving sidecar %s to %s: %v\n", sidecar, newSidecar, err)
+		r.Summary.AddFailure(sidecar, err)
+	}
+}
+
+// moveTakeoutJSON 按处理方式重命名或删除Takeout的JSON附属文件,重命名后为新文件名加.json
+// 新的JSON路径已存在时保留原JSON并记录警告,不添加_N后缀,避免JSON与文件名不再对应
+// 编辑后的文件与原文件共用的JSON随原文件处理;导入时删除方式只是不复制JSON,原文件保留时JSON也保留
+func (r *Renamer) moveTakeoutJSON(oldPath, targetPath string) {
+	if r.TakeoutJSON == TakeoutJSONKeep {
+		return
+	}
+	jsonPath, shared := r.DateChain.Takeout.Find(oldPath)
+	if jsonPath == "" || shared {
+		return
+	}
+	newJSONPath := targetPath + ".json"
+	if r.TakeoutJSON != TakeoutJSONDelete && r.Operator.Exists(newJSONPath) {
+		r.Summary.AddWarning(jsonPath, fmt.Errorf("%s已存在,JSON附属文件未移动", newJSONPath))
+		return
+	}
+	var err error
+	switch {
+	case r.TakeoutJSON == TakeoutJSONDelete:
+		if r.Copy && !r.DeleteSource {
+			return
+		}
+		err = r.Operator.Remove(jsonPath)
+	case r.Copy:
+		_, err = r.Operator.Copy(jsonPath, newJSONPath, r.DeleteSource)
+	default:
+		_, err = r.Operator.Move(jsonPath, newJSONPath)
+	}
+	if err != nil {
+		fmt.Printf("Error handling Takeout JSON %s: %v\n", jsonPath, err)
+		r.Summary.AddFailure(jsonPath, err)
+		return
+	}
+	// 导入时保留原文件的JSON仍可匹配给同名的编辑后文件
+	if !r.Copy || r.DeleteSource {
+		r.DateChain.Takeout.Remove(jsonPath)
+	}
+}
+
+// hasSiblingFile 判断附属文件所在目录中是否还有除path外同名不同扩展名的文件
+func hasSiblingFile(path, sidecar string) bool {
+	base := strings.TrimSuffix(filepath.Base(sidecar), filepath.Ext(sidecar))
+	entries, err := os.ReadDir(filepath.Dir(sidecar))
+	if err != nil {
+		return false
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || name == filepath.Base(path) || name == filepath.Base(sidecar) {
+			continue
+		}
+		if strings.TrimSuffix(name, filepath.Ext(name)) == base && !strings.EqualFold(filepath.Ext(name), ".xmp") {
+			return true
+		}
+	}
+	return false
+}
+
+// renameByCaptureTime 读取拍摄时间并生成新文件名,加入等待移动的队列
+func (r *Renamer) renameByCaptureTime(ctx context.Context, path string) error {
+	metadata := &Metadata{}
+	var toolErr error
+	if r.DateChain.NeedsMetadata() {
+		var err error
+		metadata, err = r.Metadata.Read(ctx, path)
+		// 缺少命令时仍可使用来源链中的其他来源
+		if errors.Is(err, ErrMetadataToolNotFound) {
+			metadata, toolErr = &Metadata{}, err
+		} else if err != nil {
+			// 元数据损坏等无法读取时视为没有拍摄日期,交由匹配失败的处理方式处理,不中断运行,在运行结果中提示
+			metadata = &Metadata{}
+			r.Summary.AddWarning(path, fmt.Errorf("无法读取元数据,已改用其他时间来源: %w", err))
+		}
+	}
+	date, source := r.DateChain.Resolve(path, metadata)
+	// 缺少命令只影响该文件,记录失败后继续处理其他文件
+	if date == nil && toolErr != nil {
+		r.Summary.AddFailure(path, toolErr)
+		return nil
+	}
+	var captureTime time.Time
+	if date != nil {
+		captureTime = r.TimeZone.Normalize(date)
+	}
+	// 没有拍摄日期
+	if captureTime.IsZero() {
+		switch r.MatchFailureHandlerType {
+		case MatchFailureHandlerTypeIgnore:
+			return nil
+		case MatchFailureHandlerTypeMoveToUnknownDateDir:
+			// 没有拍摄日期的文件移至unknown-date文件夹
+			targetPath := r.Namer.GetUnknownDatePath(path)
+			// 移动文件,目标目录不存在时自动创建
+			r.schedule(path, targetPath, time.Time{}, "")
+			return nil
+		case MatchFailureHandlerTypeUseFilenameDate:
+			// 先从文件名推断时间,推断不出时按文件创建时间命名
+			if date, rule := MatchFilenameDate(r.DateChain.FilenameRules, path); date != nil {
+				captureTime, source = r.TimeZone.Normalize(date), fmt.Sprintf("%s(%s)", DateSourceFilename, rule)
+				break
+			}
+			captureTime, source = r.fileCreationTime(path), DateSourceCreationTime
+		case MatchFailureHandlerTypeUseFileCreationTime:
+			// 没有拍摄日期的按文件创建时间命名
+			captureTime, source = r.fileCreationTime(path), DateSourceCreationTime
+		}
+	}
+	newFilePath := r.Namer.GetDateFilePath(captureTime, path)
+	// 当前目录处理完成后按拍摄时间顺序重命名
+	r.schedule(path, newFilePath, captureTime, source)
+	return nil
+}
+
+// fileCreationTime 获取文件创建时间并转换到统一的时区,获取失败时返回零值
+func (r *Renamer) fileCreationTime(path string) time.Time {
+	creationTime, err := GetFileCreationTime(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return r.TimeZone.Normalize(&MetadataDate{Time: creationTime, OffsetKnown: true})
+}
+
+// schedule 将文件加入等待移动的队列,在当前目录处理完成后由flush统一移动
+func (r *Renamer) schedule(oldPath, newPath string, date time.Time, source string) {
+	r.pending[oldPath] = &pendingMove{oldPath: oldPath, newPath: newPath, date: date, source: source}
+	if source != "" {
+		r.Sources[oldPath] = source
+	}
+}
+
+// flush 按拍摄时间顺序移动等待中的文件,拍摄时间相同时按原文件名排序
+func (r *Renamer) flush(bar *mpb.Bar) error {
+	moves := make([]*pendingMove, 0, len(r.pending))
+	for _, move := range r.pending {
+		moves = append(moves, move)
+	}
+	// 按拍摄时的当地时间排序,与文件名中的时间一致
+	sort.Slice(moves, func(i, j int) bool {
+		if dateI, dateJ := wallClock(moves[i].date), wallClock(moves[j].date); !dateI.Equal(dateJ) {
+			return dateI.Before(dateJ)
+		}
+		return moves[i].oldPath < moves[j].oldPath
+	})
+	for _, move := range moves {
+		delete(r.pending, move.oldPath)
+		r.move(move.oldPath, move.newPath)
+		if err := r.done(move.oldPath, move.source, bar); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// wallClock 去掉时区,只保留拍摄时的当地时间
+func wallClock(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+}
+
+// complete 文件处理完成,等待移动的文件在flush中移动后才算完成
+func (r *Renamer) complete(path string, bar *mpb.Bar) error {
+	if _, ok := r.pending[path]; ok {
+		return nil
+	}
+	return r.done(path, "", bar)
+}
+
+// done 写入检查点、更新统计及进度,source为拍摄时间的来源
+func (r *Renamer) done(path, source string, bar *mpb.Bar) error {
+	if err := r.Checkpoint.Done(path, source); err != nil {
+		return err
+	}
+	r.Summary.Processed++
+	bar.Increment()
+	return nil
+}
+
+// walkDir 遍历目录及其子目录,先处理当前目录下的文件再进入子目录,每个目录处理完成或中断时调用flush
+// 处理前先列出全部文件,整理模式下移入处理目录中的文件不会被再次处理
+func walkDir(ctx context.Context, dir string, fn func(path string, file os.FileInfo) error, flush func() error) error {
+	groups, err := listDirFiles(dir)
+	if err != nil {
+		return err
+	}
+	for _, files := range groups {
+		for _, file := range files {
+			// 收到中断信号后不再处理新文件
+			if err = ctx.Err(); err != nil {
+				break
+			}
+			if err = fn(file.path, file.info); err != nil {
+				break
+			}
+		}
+		// 已处理的文件在返回前完成移动
+		if flushErr := flush(); err == nil {
+			err = flushErr
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dirFile 待处理的文件
+type dirFile struct {
+	path string
+	info os.FileInfo
+}
+
+// listDirFiles 按处理顺序列出目录及其子目录中的文件,每个目录一组
+func listDirFiles(dir string) ([][]dirFile, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []dirFile
+	var subDirs []string
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		if entry.IsDir() {
+			subDirs = append(subDirs, path)
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, dirFile{path: path, info: info})
+	}
+	groups := [][]dirFile{files}
+	for _, subDir := range subDirs {
+		subGroups, err := listDirFiles(subDir)
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, subGroups...)
+	}
+	return groups, nil
+}
@@ -0,0 +1,80 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRenameByCaptureTimeMetadataError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "photo.jpg")
+	if err := os.WriteFile(path, []byte("not a jpeg"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	renamer, err := NewRenamer(&Options{
+		MatchFailureHandlerType: MatchFailureHandlerTypeIgnore,
+		DateSources:             []string{DateSourceExifOriginal, DateSourceModTime},
+		MetadataProviders:       []MetadataProvider{&fakeProvider{name: "fake", err: errors.New("文件头损坏")}},
+	}, NewPlanOperator())
+	if err != nil {
+		t.Fatalf("NewRenamer() error = %v", err)
+	}
+	if err = renamer.renameByCaptureTime(context.Background(), path); err != nil {
+		t.Fatalf("renameByCaptureTime() error = %v", err)
+	}
+	if source := renamer.Sources[path]; source != DateSourceModTime {
+		t.Errorf("source = %q, want %q", source, DateSourceModTime)
+	}
+	if len(renamer.Summary.Failures) != 0 {
+		t.Errorf("Failures = %v, want none", renamer.Summary.Failures)
+	}
+	if len(renamer.Summary.Warnings) != 1 || !strings.Contains(renamer.Summary.Warnings[0], "文件头损坏") {
+		t.Errorf("Warnings = %v, want one warning with the metadata error", renamer.Summary.Warnings)
+	}
+}
+
+func TestMoveTakeoutJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		occupied bool // 新的JSON路径已存在
+	}{
+		{"随文件重命名", false},
+		{"新的JSON路径已存在", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeTakeoutFiles(t, dir, "photo.jpg", "photo.jpg.json")
+			newPath := filepath.Join(dir, "IMG_20200101_180000.JPG")
+			if tt.occupied {
+				writeTakeoutFiles(t, dir, "IMG_20200101_180000.JPG.json")
+			}
+			renamer, err := NewRenamer(&Options{
+				MatchFailureHandlerType: MatchFailureHandlerTypeIgnore,
+				DateSources:             []string{DateSourceTakeout},
+			}, NewDiskOperator())
+			if err != nil {
+				t.Fatalf("NewRenamer() error = %v", err)
+			}
+			renamer.move(filepath.Join(dir, "photo.jpg"), newPath)
+			if _, err = os.Stat(newPath); err != nil {
+				t.Fatalf("%s not moved: %v", newPath, err)
+			}
+			// JSON只以文件的新名称命名,不添加_N后缀
+			if _, err = os.Stat(filepath.Join(dir, "IMG_20200101_180000.JPG_1.json")); !os.IsNotExist(err) {
+				t.Errorf("JSON renamed with a conflict suffix")
+			}
+			_, err = os.Stat(filepath.Join(dir, "photo.jpg.json"))
+			if tt.occupied {
+				if err != nil || len(renamer.Summary.Warnings) != 1 {
+					t.Errorf("original JSON error = %v, Warnings = %v, want JSON kept with one warning", err, renamer.Summary.Warnings)
+				}
+			} else if !os.IsNotExist(err) || len(renamer.Summary.Warnings) != 0 {
+				t.Errorf("original JSON error = %v, Warnings = %v, want JSON moved", err, renamer.Summary.Warnings)
+			}
+		})
+	}
+}
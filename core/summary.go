@@ -0,0 +1,45 @@
+package core
+
+import (
+	"fmt"
+
+	"github.com/fatih/color"
+)
+
+// Summary 运行结果统计
+type Summary struct {
+	Processed int      // 已处理的文件数量
+	Moved     int      // 重命名或移动的文件数量
+	Copied    int      // 复制的文件数量
+	Failures  []string // 处理失败的文件及原因
+	Warnings  []string // 已处理但需要注意的文件及原因,如元数据损坏时改用其他时间
+}
+
+func NewSummary() *Summary {
+	return &Summary{}
+}
+
+// AddFailure 记录处理失败的文件
+func (s *Summary) AddFailure(path string, err error) {
+	s.Failures = append(s.Failures, fmt.Sprintf("%s: %v", path, err))
+}
+
+// AddWarning 记录已处理但需要注意的文件
+func (s *Summary) AddWarning(path string, err error) {
+	s.Warnings = append(s.Warnings, fmt.Sprintf("%s: %v", path, err))
+}
+
+// Print 打印运行结果
+func (s *Summary) Print() {
+	if s.Copied > 0 {
+		color.New(color.FgBlue).Add(color.Bold).Printf("\n共处理%d个文件,复制%d个,重命名或移动%d个,失败%d个\n", s.Processed, s.Copied, s.Moved, len(s.Failures))
+	} else {
+		color.New(color.FgBlue).Add(color.Bold).Printf("\n共处理%d个文件,重命名或移动%d个,失败%d个\n", s.Processed, s.Moved, len(s.Failures))
+	}
+	for _, failure := range s.Failures {
+		color.New(color.FgRed).Println("失败 " + failure)
+	}
+	for _, warning := range s.Warnings {
+		color.New(color.FgYellow).Println("警告 " + warning)
+	}
+}
@@ -0,0 +1,181 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/thoas/go-funk"
+	"github.com/tidwall/gjson"
+)
+
+// Takeout JSON附属文件的处理方式
+const (
+	TakeoutJSONRename = "rename" // 随原文件重命名
+	TakeoutJSONDelete = "delete" // 原文件处理后删除
+	TakeoutJSONKeep   = "keep"   // 保持不变
+)
+
+// DefaultTakeoutJSON 默认的Takeout JSON附属文件处理方式
+const DefaultTakeoutJSON = TakeoutJSONRename
+
+// TakeoutJSONTextMap Takeout JSON附属文件处理方式说明
+var TakeoutJSONTextMap = map[string]string{
+	TakeoutJSONRename: "随原文件重命名或移动,如photo.jpg.json -> IMG_20250606_121601.JPG.json",
+	TakeoutJSONDelete: "原文件处理后删除,文件内容记录在操作日志中,可通过undo恢复",
+	TakeoutJSONKeep:   "保持不变",
+}
+
+// takeoutNameLimit Takeout导出时JSON文件名(不含.json)最多保留的字符数,超出部分被截断
+const takeoutNameLimit = 46
+
+// Takeout JSON文件名中的后缀
+const (
+	takeoutSupplementalSuffix = ".supplemental-metadata" // 新版Takeout的后缀,如photo.jpg.supplemental-metadata.json
+	takeoutEditedSuffix       = "-edited"                // 在Google相册中编辑后的文件,与原文件共用JSON
+)
+
+// takeoutDuplicatePattern 重名文件的序号,如photo(1).jpg中的(1)
+var takeoutDuplicatePattern = regexp.MustCompile(`^(.*)\((\d+)\)$`)
+
+// TakeoutJSONActions 返回排序后的全部Takeout JSON附属文件处理方式
+func TakeoutJSONActions() []string {
+	actions := funk.Keys(TakeoutJSONTextMap).([]string)
+	sort.Strings(actions)
+	return actions
+}
+
+// ValidateTakeoutJSON 校验Takeout JSON附属文件处理方式
+func ValidateTakeoutJSON(action string) error {
+	if TakeoutJSONTextMap[action] == "" {
+		return fmt.Errorf("Takeout JSON处理方式%s不存在,可用方式:%s", action, strings.Join(TakeoutJSONActions(), ","))
+	}
+	return nil
+}
+
+// TakeoutIndex 缓存各目录中的JSON文件名,用于查找被截断的JSON,避免每个文件都读取一次目录,每次运行创建一个
+type TakeoutIndex struct {
+	dirs map[string][]string // 目录中JSON文件的文件名(不含.json)
+}
+
+func NewTakeoutIndex() *TakeoutIndex {
+	return &TakeoutIndex{dirs: make(map[string][]string)}
+}
+
+// Find 查找文件对应的Google Takeout JSON附属文件,依次匹配photo.jpg.json、photo.jpg.supplemental-metadata.json、
+// 重名文件photo(1).jpg对应的photo.jpg(1).json、编辑后的photo-edited.jpg对应的photo.jpg.json,
+// 以及文件名过长被截断的photo.jp.json等;没有时返回空,shared表示JSON属于同名的原文件,不应随该文件处理
+func (x *TakeoutIndex) Find(path string) (jsonPath string, shared bool) {
+	dir, name := filepath.Dir(path), filepath.Base(path)
+	ext := filepath.Ext(name)
+	stem := strings.TrimSuffix(name, ext)
+	candidates := []string{name + ".json", name + takeoutSupplementalSuffix + ".json"}
+	if matches := takeoutDuplicatePattern.FindStringSubmatch(stem); matches != nil {
+		original := matches[1] + ext
+		candidates = append(candidates,
+			fmt.Sprintf("%s(%s).json", original, matches[2]),
+			fmt.Sprintf("%s%s(%s).json", original, takeoutSupplementalSuffix, matches[2]))
+	}
+	for _, candidate := range candidates {
+		if isRegularFile(filepath.Join(dir, candidate)) {
+			return filepath.Join(dir, candidate), false
+		}
+	}
+	if jsonPath = x.findTruncated(dir, name); jsonPath != "" {
+		return jsonPath, false
+	}
+	original := strings.TrimSuffix(stem, takeoutEditedSuffix)
+	if original == stem {
+		return "", false
+	}
+	original += ext
+	for _, candidate := range []string{original + ".json", original + takeoutSupplementalSuffix + ".json"} {
+		if isRegularFile(filepath.Join(dir, candidate)) {
+			return filepath.Join(dir, candidate), true
+		}
+	}
+	if jsonPath = x.findTruncated(dir, original); jsonPath != "" {
+		return jsonPath, true
+	}
+	return "", false
+}
+
+// ReadDate 读取Takeout JSON附属文件中的photoTakenTime,为UTC时间戳,没有附属文件或没有该时间时返回nil
+func (x *TakeoutIndex) ReadDate(path string) *MetadataDate {
+	jsonPath, _ := x.Find(path)
+	if jsonPath == "" {
+		return nil
+	}
+	data, err := os.ReadFile(jsonPath)
+	if err != nil || !gjson.ValidBytes(data) {
+		return nil
+	}
+	timestamp := gjson.GetBytes(data, "photoTakenTime.timestamp").Int()
+	if timestamp <= 0 {
+		return nil
+	}
+	return &MetadataDate{Time: time.Unix(timestamp, 0).UTC(), OffsetKnown: true}
+}
+
+// Remove JSON被移动或删除后将其移出缓存,避免再匹配给其他文件
+func (x *TakeoutIndex) Remove(jsonPath string) {
+	dir, stem := filepath.Dir(jsonPath), strings.TrimSuffix(filepath.Base(jsonPath), ".json")
+	stems := x.dirs[dir]
+	for i, cached := range stems {
+		if cached == stem {
+			x.dirs[dir] = append(stems[:i:i], stems[i+1:]...)
+			return
+		}
+	}
+}
+
+// jsonStems 返回目录中JSON文件的文件名(不含.json),首次使用时读取目录
+func (x *TakeoutIndex) jsonStems(dir string) []string {
+	if stems, ok := x.dirs[dir]; ok {
+		return stems
+	}
+	var stems []string
+	// 目录无法读取时缓存空列表,不再重复读取
+	entries, _ := os.ReadDir(dir)
+	for _, entry := range entries {
+		if stem := strings.TrimSuffix(entry.Name(), ".json"); !entry.IsDir() && stem != entry.Name() {
+			stems = append(stems, stem)
+		}
+	}
+	x.dirs[dir] = stems
+	return stems
+}
+
+// findTruncated 查找文件名被截断的JSON,如photo.jp.json,多个匹配时使用截断最少的
+// 截断后至少保留扩展名的点号,或达到Takeout的长度上限,避免photo.json匹配photo.jpg
+func (x *TakeoutIndex) findTruncated(dir, name string) string {
+	full := name + takeoutSupplementalSuffix
+	minLength := len(strings.TrimSuffix(name, filepath.Ext(name))) + 1
+	var match string
+	for _, stem := range x.jsonStems(dir) {
+		if !strings.HasPrefix(full, stem) {
+			continue
+		}
+		if len(stem) < minLength && utf8.RuneCountInString(stem) < takeoutNameLimit {
+			continue
+		}
+		if len(stem) > len(match) {
+			match = stem
+		}
+	}
+	if match == "" || !isRegularFile(filepath.Join(dir, match+".json")) {
+		return ""
+	}
+	return filepath.Join(dir, match+".json")
+}
+
+// isRegularFile 判断路径是否为已存在的文件
+func isRegularFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
@@ -0,0 +1,92 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeTakeoutFiles 在目录中创建文件,.json文件写入photoTakenTime
+func writeTakeoutFiles(t *testing.T, dir string, names ...string) {
+	t.Helper()
+	for _, name := range names {
+		data := []byte("photo")
+		if strings.HasSuffix(name, ".json") {
+			data = []byte(`{"title":"photo.jpg","photoTakenTime":{"timestamp":"1577872800","formatted":"2020年1月1日 UTC 10:00:00"}}`)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestTakeoutIndexFind(t *testing.T) {
+	long := strings.Repeat("x", 50) + ".jpg"
+	tests := []struct {
+		name       string
+		files      []string
+		path       string
+		want       string
+		wantShared bool
+	}{
+		{"同名JSON", []string{"photo.jpg", "photo.jpg.json"}, "photo.jpg", "photo.jpg.json", false},
+		{"supplemental-metadata", []string{"photo.jpg", "photo.jpg.supplemental-metadata.json"}, "photo.jpg", "photo.jpg.supplemental-metadata.json", false},
+		{"重名文件的序号在扩展名后", []string{"photo(1).jpg", "photo.jpg(1).json"}, "photo(1).jpg", "photo.jpg(1).json", false},
+		{"重名文件的序号在扩展名前", []string{"photo(1).jpg", "photo(1).jpg.json"}, "photo(1).jpg", "photo(1).jpg.json", false},
+		{"截断的扩展名", []string{"photo.jpg", "photo.jp.json"}, "photo.jpg", "photo.jp.json", false},
+		{"截断的supplemental-metadata", []string{"photo.jpg", "photo.jpg.supplemental-met.json"}, "photo.jpg", "photo.jpg.supplemental-met.json", false},
+		{"文件名超出长度上限", []string{long, long[:takeoutNameLimit] + ".json"}, long, long[:takeoutNameLimit] + ".json", false},
+		{"编辑后的文件共用原文件的JSON", []string{"photo-edited.jpg", "photo.jpg.json"}, "photo-edited.jpg", "photo.jpg.json", true},
+		{"不匹配只有文件名的JSON", []string{"photo.jpg", "photo.json"}, "photo.jpg", "", false},
+		{"不匹配其他文件的JSON", []string{"IMG_1.jpg", "IMG_10.jpg.json"}, "IMG_1.jpg", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeTakeoutFiles(t, dir, tt.files...)
+			jsonPath, shared := NewTakeoutIndex().Find(filepath.Join(dir, tt.path))
+			want := ""
+			if tt.want != "" {
+				want = filepath.Join(dir, tt.want)
+			}
+			if jsonPath != want || shared != tt.wantShared {
+				t.Errorf("Find() = %q, %v, want %q, %v", jsonPath, shared, want, tt.wantShared)
+			}
+		})
+	}
+}
+
+func TestTakeoutIndexReadDate(t *testing.T) {
+	dir := t.TempDir()
+	writeTakeoutFiles(t, dir, "photo.jpg", "photo.jp.json", "other.jpg")
+	index := NewTakeoutIndex()
+	date := index.ReadDate(filepath.Join(dir, "photo.jpg"))
+	if want := time.Date(2020, 1, 1, 10, 0, 0, 0, time.UTC); date == nil || !date.Time.Equal(want) || !date.OffsetKnown {
+		t.Fatalf("ReadDate() = %v, want %v", date, want)
+	}
+	if date = index.ReadDate(filepath.Join(dir, "other.jpg")); date != nil {
+		t.Errorf("ReadDate() = %v, want nil", date)
+	}
+}
+
+func TestTakeoutIndexCachesDirectory(t *testing.T) {
+	dir := t.TempDir()
+	writeTakeoutFiles(t, dir, "photo.jpg", "photo.jp.json", "video.mp4")
+	index := NewTakeoutIndex()
+	if jsonPath, _ := index.Find(filepath.Join(dir, "photo.jpg")); jsonPath != filepath.Join(dir, "photo.jp.json") {
+		t.Fatalf("Find() = %q, want photo.jp.json", jsonPath)
+	}
+	// 目录只读取一次,之后创建的截断JSON不在缓存中
+	writeTakeoutFiles(t, dir, "video.mp.json")
+	if jsonPath, _ := index.Find(filepath.Join(dir, "video.mp4")); jsonPath != "" {
+		t.Errorf("Find() = %q, want cached listing without video.mp.json", jsonPath)
+	}
+	// 移出缓存后不再匹配给其他文件
+	index.Remove(filepath.Join(dir, "photo.jp.json"))
+	writeTakeoutFiles(t, dir, "photo.jpeg")
+	if jsonPath, _ := index.Find(filepath.Join(dir, "photo.jpeg")); jsonPath != "" {
+		t.Errorf("Find() = %q after Remove, want empty", jsonPath)
+	}
+}
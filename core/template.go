@@ -0,0 +1,290 @@
+package core
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/thoas/go-funk"
+)
+
+// DefaultTemplate 默认文件名模板,与IMG_20250606_121601.JPG格式一致
+const DefaultTemplate = "{prefix}_{YYYY}{MM}{DD}_{hh}{mm}{ss}.{ext}"
+
+// DefaultFolderTemplate 默认文件夹模板,整理后的路径如2025/2025-06/IMG_20250606_121601.JPG
+const DefaultFolderTemplate = "{YYYY}/{YYYY}-{MM}"
+
+// 模板变量
+const (
+	TemplateTokenPrefix  = "prefix"  // 前缀
+	TemplateTokenYear    = "YYYY"    // 四位年份
+	TemplateTokenYear2   = "YY"      // 两位年份
+	TemplateTokenMonth   = "MM"      // 月
+	TemplateTokenDay     = "DD"      // 日
+	TemplateTokenHour    = "hh"      // 时(24小时制)
+	TemplateTokenMinute  = "mm"      // 分
+	TemplateTokenSecond  = "ss"      // 秒
+	TemplateTokenMilli   = "ms"      // 毫秒
+	TemplateTokenDate    = "date"    // 日期
+	TemplateTokenTime    = "time"    // 时间
+	TemplateTokenName    = "name"    // 原文件名
+	TemplateTokenExt     = "ext"     // 扩展名
+	TemplateTokenCounter = "counter" // 序号
+	TemplateTokenMake    = "make"    // 相机厂商
+	TemplateTokenModel   = "model"   // 相机型号
+	TemplateTokenCamera  = "camera"  // 相机厂商及型号
+)
+
+// TemplateTokenTextMap 模板变量说明
+var TemplateTokenTextMap = map[string]string{
+	TemplateTokenPrefix:  "前缀,如IMG/VID/FIL",
+	TemplateTokenYear:    "拍摄时间的四位年份,如2025",
+	TemplateTokenYear2:   "拍摄时间的两位年份,如25",
+	TemplateTokenMonth:   "拍摄时间的月,如06",
+	TemplateTokenDay:     "拍摄时间的日,如06",
+	TemplateTokenHour:    "拍摄时间的时(24小时制),如12",
+	TemplateTokenMinute:  "拍摄时间的分,如16",
+	TemplateTokenSecond:  "拍摄时间的秒,如01",
+	TemplateTokenMilli:   "拍摄时间的毫秒,如045,用于区分同一秒内的连拍照片,没有亚秒信息时为000",
+	TemplateTokenDate:    "拍摄日期,如20250606",
+	TemplateTokenTime:    "拍摄时间,如121601",
+	TemplateTokenName:    "原文件名(不含扩展名)",
+	TemplateTokenExt:     "扩展名(不含.),模板中没有该变量时自动追加到末尾",
+	TemplateTokenCounter: "本次运行的序号,{counter:4}表示补零到4位",
+	TemplateTokenMake:    "相机厂商,如Canon",
+	TemplateTokenModel:   "相机型号,如Canon EOS R6",
+	TemplateTokenCamera:  "相机厂商及型号,型号已包含厂商时只保留型号",
+}
+
+// templateDateLayoutMap 日期类模板变量对应的时间格式
+var templateDateLayoutMap = map[string]string{
+	TemplateTokenYear:   "2006",
+	TemplateTokenYear2:  "06",
+	TemplateTokenMonth:  "01",
+	TemplateTokenDay:    "02",
+	TemplateTokenHour:   "15",
+	TemplateTokenMinute: "04",
+	TemplateTokenSecond: "05",
+	TemplateTokenDate:   "20060102",
+	TemplateTokenTime:   "150405",
+}
+
+// templateIllegalChars 文件名中不允许出现的字符
+const templateIllegalChars = `/\:*?"<>|`
+
+// TemplateData 模板渲染数据
+type TemplateData struct {
+	Time    time.Time // 拍摄时间
+	Prefix  string    // 前缀
+	Name    string    // 原文件名(不含扩展名)
+	Ext     string    // 扩展名(不含.)
+	Counter int       // 序号
+	Make    string    // 相机厂商
+	Model   string    // 相机型号
+}
+
+// Template 文件名模板
+type Template struct {
+	Text  string
+	parts []*templatePart
+}
+
+// templatePart 模板片段,token为空时为普通文本
+type templatePart struct {
+	literal string
+	token   string
+	width   int
+}
+
+// ParseTemplate 解析并校验文件名模板
+func ParseTemplate(text string) (*Template, error) {
+	if strings.TrimSpace(text) == "" {
+		return nil, fmt.Errorf("文件名模板不能为空")
+	}
+	t, err := parseTemplateParts(text)
+	if err != nil {
+		return nil, err
+	}
+	if !t.uses(TemplateTokenName, TemplateTokenCounter) && !t.uses(funk.Keys(templateDateLayoutMap).([]string)...) {
+		return nil, fmt.Errorf("文件名模板%s中至少需要包含一个日期、原文件名或序号变量", text)
+	}
+	if !t.uses(TemplateTokenExt) {
+		t.parts = append(t.parts, &templatePart{literal: "."}, &templatePart{token: TemplateTokenExt})
+	}
+	return t, nil
+}
+
+// parseTemplateParts 将模板拆分为普通文本及变量
+func parseTemplateParts(text string) (*Template, error) {
+	t := &Template{Text: text}
+	rest := text
+	for rest != "" {
+		start := strings.IndexAny(rest, "{}")
+		if start < 0 {
+			t.parts = append(t.parts, &templatePart{literal: rest})
+			break
+		}
+		if rest[start] == '}' {
+			return nil, fmt.Errorf("模板%s中存在多余的}", text)
+		}
+		if start > 0 {
+			t.parts = append(t.parts, &templatePart{literal: rest[:start]})
+		}
+		end := strings.IndexByte(rest[start:], '}')
+		if end < 0 {
+			return nil, fmt.Errorf("模板%s中的{没有闭合", text)
+		}
+		part, err := parseTemplateToken(rest[start+1 : start+end])
+		if err != nil {
+			return nil, err
+		}
+		t.parts = append(t.parts, part)
+		rest = rest[start+end+1:]
+	}
+	for _, part := range t.parts {
+		if strings.ContainsAny(part.literal, templateIllegalChars) {
+			return nil, fmt.Errorf("模板%s中不能包含以下字符:%s", text, templateIllegalChars)
+		}
+	}
+	return t, nil
+}
+
+// parseTemplateToken 解析{}中的模板变量,支持{counter:4}形式的宽度
+func parseTemplateToken(token string) (*templatePart, error) {
+	name, widthText, hasWidth := strings.Cut(token, ":")
+	if _, ok := TemplateTokenTextMap[name]; !ok {
+		return nil, fmt.Errorf("不支持的模板变量{%s},可用变量:%s", token, strings.Join(TemplateTokens(), ","))
+	}
+	part := &templatePart{token: name}
+	if hasWidth {
+		width, err := strconv.Atoi(widthText)
+		if name != TemplateTokenCounter || err != nil || width <= 0 || width > 10 {
+			return nil, fmt.Errorf("模板变量{%s}格式错误,仅{counter}支持1-10的宽度", token)
+		}
+		part.width = width
+	}
+	return part, nil
+}
+
+// TemplateTokens 返回排序后的全部模板变量
+func TemplateTokens() []string {
+	tokens := funk.Keys(TemplateTokenTextMap).([]string)
+	sort.Strings(tokens)
+	for i, token := range tokens {
+		tokens[i] = "{" + token + "}"
+	}
+	return tokens
+}
+
+// UsesCamera 模板是否用到相机信息,用不到时无需读取
+func (t *Template) UsesCamera() bool {
+	return t.uses(TemplateTokenMake, TemplateTokenModel, TemplateTokenCamera)
+}
+
+// uses 模板是否包含任一指定变量
+func (t *Template) uses(tokens ...string) bool {
+	for _, part := range t.parts {
+		if part.token != "" && funk.ContainsString(tokens, part.token) {
+			return true
+		}
+	}
+	return false
+}
+
+// Render 渲染文件名(含扩展名),去掉因变量为空而残留在首尾的分隔符
+func (t *Template) Render(data *TemplateData) string {
+	result := t.renderParts(data)
+	ext := "." + data.Ext
+	if strings.HasSuffix(result, ext) {
+		return strings.Trim(strings.TrimSuffix(result, ext), " -_.") + ext
+	}
+	return strings.Trim(result, " -_.")
+}
+
+// renderParts 依次渲染模板片段
+func (t *Template) renderParts(data *TemplateData) string {
+	var result strings.Builder
+	for _, part := range t.parts {
+		if part.token == "" {
+			result.WriteString(part.literal)
+			continue
+		}
+		result.WriteString(t.renderToken(part, data))
+	}
+	return result.String()
+}
+
+// renderToken 渲染单个模板变量
+func (t *Template) renderToken(part *templatePart, data *TemplateData) string {
+	if layout, ok := templateDateLayoutMap[part.token]; ok {
+		return data.Time.Format(layout)
+	}
+	var value string
+	switch part.token {
+	case TemplateTokenPrefix:
+		value = data.Prefix
+	case TemplateTokenMilli:
+		value = fmt.Sprintf("%03d", data.Time.Nanosecond()/int(time.Millisecond))
+	case TemplateTokenName:
+		value = data.Name
+	case TemplateTokenExt:
+		value = data.Ext
+	case TemplateTokenCounter:
+		value = fmt.Sprintf("%0*d", part.width, data.Counter)
+	case TemplateTokenMake:
+		value = data.Make
+	case TemplateTokenModel:
+		value = data.Model
+	case TemplateTokenCamera:
+		value = data.Model
+		if data.Make != "" && !strings.HasPrefix(strings.ToLower(data.Model), strings.ToLower(data.Make)) {
+			value = strings.TrimSpace(data.Make + " " + data.Model)
+		}
+	}
+	// 变量值中的非法字符替换为-
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(templateIllegalChars, r) {
+			return '-'
+		}
+		return r
+	}, value)
+}
+
+// FolderTemplate 文件夹模板,以/分隔多级文件夹,每级文件夹与文件名模板使用相同的变量
+type FolderTemplate struct {
+	Text   string
+	levels []*Template
+}
+
+// ParseFolderTemplate 解析并校验文件夹模板
+func ParseFolderTemplate(text string) (*FolderTemplate, error) {
+	if strings.TrimSpace(text) == "" {
+		return nil, fmt.Errorf("文件夹模板不能为空")
+	}
+	t := &FolderTemplate{Text: text}
+	for _, level := range strings.Split(strings.Trim(text, "/"), "/") {
+		if strings.TrimSpace(level) == "" || level == "." || level == ".." {
+			return nil, fmt.Errorf("文件夹模板%s中存在空的或非法的文件夹名", text)
+		}
+		levelTemplate, err := parseTemplateParts(level)
+		if err != nil {
+			return nil, err
+		}
+		t.levels = append(t.levels, levelTemplate)
+	}
+	return t, nil
+}
+
+// Render 渲染相对路径,因变量为空而为空的文件夹直接省略
+func (t *FolderTemplate) Render(data *TemplateData) string {
+	levels := make([]string, 0, len(t.levels))
+	for _, level := range t.levels {
+		if name := strings.Trim(level.renderParts(data), " -_."); name != "" {
+			levels = append(levels, name)
+		}
+	}
+	return filepath.Join(levels...)
+}
@@ -0,0 +1,222 @@
+package core
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+
+	exifcommon "github.com/dsoprea/go-exif/v3/common"
+	"github.com/thoas/go-funk"
+)
+
+// tiffMaxValueSize 读取的单个标签值的最大长度,防止异常文件占用过多内存
+const tiffMaxValueSize = 4 << 20
+
+// tiffMaxIFDs 最多读取的IFD数量,防止异常文件中的循环引用
+const tiffMaxIFDs = 16
+
+// 指向子IFD的标签
+const (
+	tiffTagExifIFD = 0x8769 // Exif IFD
+	tiffTagGPSIFD  = 0x8825 // GPS IFD
+)
+
+// tiffXMPTags IFD0中保存XMP的标签
+var tiffXMPTags = map[uint16]string{
+	0x02bc: "XMLPacket",
+}
+
+// tiffIFD0Tags IFD0中读取的标签
+var tiffIFD0Tags = map[uint16]string{
+	0x0100: "ImageWidth",
+	0x0101: "ImageLength",
+	0x010f: "Make",
+	0x0110: "Model",
+	0x0132: "DateTime",
+}
+
+// tiffExifTags Exif IFD中读取的标签
+var tiffExifTags = map[uint16]string{
+	0x9003: "DateTimeOriginal",
+	0x9004: "DateTimeDigitized",
+	0x9010: "OffsetTime",
+	0x9011: "OffsetTimeOriginal",
+	0x9012: "OffsetTimeDigitized",
+	0x9290: "SubSecTime",
+	0x9291: "SubSecTimeOriginal",
+	0x9292: "SubSecTimeDigitized",
+	0xa002: "PixelXDimension",
+	0xa003: "PixelYDimension",
+}
+
+// tiffGPSTags GPS IFD中读取的标签
+var tiffGPSTags = map[uint16]string{
+	0x0001: "GPSLatitudeRef",
+	0x0002: "GPSLatitude",
+	0x0003: "GPSLongitudeRef",
+	0x0004: "GPSLongitude",
+}
+
+// errNotTIFF 文件不是TIFF结构
+var errNotTIFF = errors.New("不是有效的TIFF结构")
+
+// IsTIFFRaw 判断文件是否为以TIFF结构保存的RAW格式或TIFF图片
+func IsTIFFRaw(path string) bool {
+	extensions := []string{
+		".CR2", ".NEF", ".NRW", ".ARW", ".SR2", ".ORF", ".RW2", ".PEF", ".SRW", ".3FR", ".IIQ", ".DNG", ".TIF", ".TIFF",
+	}
+	return funk.ContainsString(extensions, GetExt(path))
+}
+
+// GetTIFFExifTags 从文件开头的TIFF头读取IFD0及其中的Exif、GPS IFD,无需将整个RAW文件读入内存
+func GetTIFFExifTags(filename string) (map[string]interface{}, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	tags := make(map[string]interface{})
+	if err = readTIFFTags(file, 0, tiffIFD0Tags, tags); err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+// GetTIFFXMP 读取TIFF结构RAW中IFD0的XMLPacket标签保存的XMP,没有时返回nil
+func GetTIFFXMP(filename string) ([]byte, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	tags := make(map[string]interface{})
+	if err = readTIFFTags(file, 0, tiffXMPTags, tags); err != nil {
+		return nil, err
+	}
+	packet, _ := tags["XMLPacket"].([]byte)
+	return packet, nil
+}
+
+// tiffReader 读取TIFF结构,偏移均相对于TIFF头
+type tiffReader struct {
+	r     io.ReaderAt
+	base  int64
+	order binary.ByteOrder
+	tags  map[string]interface{}
+	ifds  int
+}
+
+// readTIFFTags 读取base处TIFF结构中首个IFD的标签,names为该IFD中读取的标签,同名标签保留首个
+func readTIFFTags(r io.ReaderAt, base int64, names map[uint16]string, tags map[string]interface{}) error {
+	header := make([]byte, 8)
+	if _, err := r.ReadAt(header, base); err != nil {
+		return errNotTIFF
+	}
+	reader := &tiffReader{r: r, base: base, tags: tags}
+	switch string(header[:2]) {
+	case "II":
+		reader.order = binary.LittleEndian
+	case "MM":
+		reader.order = binary.BigEndian
+	default:
+		return errNotTIFF
+	}
+	// 标准TIFF为42,ORF为RO/RS,RW2为0x55
+	if magic := reader.order.Uint16(header[2:4]); !funk.Contains([]uint16{42, 0x4f52, 0x5352, 0x55}, magic) {
+		return errNotTIFF
+	}
+	return reader.readIFD(int64(reader.order.Uint32(header[4:8])), names, true)
+}
+
+// tiffSubIFD IFD0中指向的子IFD
+type tiffSubIFD struct {
+	offset int64
+	names  map[uint16]string
+}
+
+// readIFD 读取IFD中的标签,subIFDs为true时一并读取其中指向的Exif、GPS IFD
+func (t *tiffReader) readIFD(offset int64, names map[uint16]string, subIFDs bool) error {
+	if t.ifds++; t.ifds > tiffMaxIFDs {
+		return nil
+	}
+	countData := make([]byte, 2)
+	if _, err := t.r.ReadAt(countData, t.base+offset); err != nil {
+		return errNotTIFF
+	}
+	count := int(t.order.Uint16(countData))
+	entries := make([]byte, 12*count)
+	if _, err := t.r.ReadAt(entries, t.base+offset+2); err != nil {
+		return errNotTIFF
+	}
+	var children []tiffSubIFD
+	for i := 0; i < count; i++ {
+		entry := entries[i*12 : i*12+12]
+		tag := t.order.Uint16(entry[:2])
+		switch {
+		case subIFDs && tag == tiffTagExifIFD:
+			children = append(children, tiffSubIFD{offset: int64(t.order.Uint32(entry[8:12])), names: tiffExifTags})
+			continue
+		case subIFDs && tag == tiffTagGPSIFD:
+			children = append(children, tiffSubIFD{offset: int64(t.order.Uint32(entry[8:12])), names: tiffGPSTags})
+			continue
+		}
+		name, ok := names[tag]
+		if !ok {
+			continue
+		}
+		if _, exists := t.tags[name]; exists {
+			continue
+		}
+		if value := t.readValue(entry); value != nil {
+			t.tags[name] = value
+		}
+	}
+	for _, child := range children {
+		if err := t.readIFD(child.offset, child.names, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readValue 读取标签值,ASCII返回字符串,BYTE/UNDEFINED返回字节,SHORT/LONG/RATIONAL返回与go-exif一致的切片,其他类型返回nil
+func (t *tiffReader) readValue(entry []byte) interface{} {
+	valueType := t.order.Uint16(entry[2:4])
+	count := int64(t.order.Uint32(entry[4:8]))
+	unitSize := map[uint16]int64{1: 1, 2: 1, 3: 2, 4: 4, 5: 8, 7: 1}[valueType]
+	if unitSize == 0 || count == 0 || unitSize*count > tiffMaxValueSize {
+		return nil
+	}
+	data := entry[8:12]
+	if size := unitSize * count; size > 4 {
+		data = make([]byte, size)
+		if _, err := t.r.ReadAt(data, t.base+int64(t.order.Uint32(entry[8:12]))); err != nil {
+			return nil
+		}
+	}
+	switch valueType {
+	case 1, 7:
+		return append([]byte{}, data[:count]...)
+	case 2:
+		return exifString(string(data[:count]))
+	case 3:
+		values := make([]uint16, count)
+		for i := range values {
+			values[i] = t.order.Uint16(data[i*2:])
+		}
+		return values
+	case 4:
+		values := make([]uint32, count)
+		for i := range values {
+			values[i] = t.order.Uint32(data[i*4:])
+		}
+		return values
+	default:
+		values := make([]exifcommon.Rational, count)
+		for i := range values {
+			values[i] = exifcommon.Rational{Numerator: t.order.Uint32(data[i*8:]), Denominator: t.order.Uint32(data[i*8+4:])}
+		}
+		return values
+	}
+}
@@ -0,0 +1,144 @@
+package core
+
+import (
+	"context"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+)
+
+// buildTIFF 构造TIFF结构,ifd0及exif中的标签值均为ASCII,exif不为空时在IFD0中添加指向Exif IFD的标签
+func buildTIFF(order binary.ByteOrder, magic uint16, ifd0, exif map[uint16]string) []byte {
+	header := []byte("MM\x00\x00\x00\x00\x00\x00")
+	if order == binary.ByteOrder(binary.LittleEndian) {
+		header[0], header[1] = 'I', 'I'
+	}
+	order.PutUint16(header[2:], magic)
+	order.PutUint32(header[4:], 8)
+	ifd0Count := len(ifd0)
+	if len(exif) > 0 {
+		ifd0Count++
+	}
+	exifOffset := 8 + 2 + 12*ifd0Count + 4
+	dataOffset := exifOffset
+	if len(exif) > 0 {
+		dataOffset += 2 + 12*len(exif) + 4
+	}
+	var data []byte
+	buildIFD := func(values map[uint16]string, exifPointer bool) []byte {
+		tags := make([]int, 0, len(values))
+		for tag := range values {
+			tags = append(tags, int(tag))
+		}
+		sort.Ints(tags)
+		count := len(tags)
+		if exifPointer {
+			count++
+		}
+		ifd := make([]byte, 2)
+		order.PutUint16(ifd, uint16(count))
+		for _, tag := range tags {
+			value := append([]byte(values[uint16(tag)]), 0)
+			entry := make([]byte, 12)
+			order.PutUint16(entry, uint16(tag))
+			order.PutUint16(entry[2:], 2)
+			order.PutUint32(entry[4:], uint32(len(value)))
+			if len(value) <= 4 {
+				copy(entry[8:], value)
+			} else {
+				order.PutUint32(entry[8:], uint32(dataOffset+len(data)))
+				data = append(data, value...)
+			}
+			ifd = append(ifd, entry...)
+		}
+		if exifPointer {
+			entry := make([]byte, 12)
+			order.PutUint16(entry, tiffTagExifIFD)
+			order.PutUint16(entry[2:], 4)
+			order.PutUint32(entry[4:], 1)
+			order.PutUint32(entry[8:], uint32(exifOffset))
+			ifd = append(ifd, entry...)
+		}
+		return append(ifd, 0, 0, 0, 0)
+	}
+	result := append(header, buildIFD(ifd0, len(exif) > 0)...)
+	if len(exif) > 0 {
+		result = append(result, buildIFD(exif, false)...)
+	}
+	return append(result, data...)
+}
+
+// buildJPEG 构造只包含APP1 EXIF的JPEG,用于RAW中内嵌的预览图
+func buildJPEG(tiff []byte) []byte {
+	app1 := append([]byte("Exif\x00\x00"), tiff...)
+	length := make([]byte, 2)
+	binary.BigEndian.PutUint16(length, uint16(len(app1)+2))
+	jpeg := append([]byte{0xff, 0xd8, 0xff, 0xe1}, length...)
+	return append(append(jpeg, app1...), 0xff, 0xd9)
+}
+
+// testRawExif 测试RAW中Exif IFD的标签,拍摄时间为2023-04-05 06:07:08.420 +09:00
+var testRawExif = map[uint16]string{
+	0x9003: "2023:04:05 06:07:08",
+	0x9291: "42",
+	0x9011: "+09:00",
+}
+
+// testRawCaptureTime testRawExif对应的拍摄时间
+var testRawCaptureTime = time.Date(2023, 4, 5, 6, 7, 8, 420*int(time.Millisecond), time.FixedZone("+09:00", 9*3600))
+
+// assertRawCaptureTime 使用exif读取方式读取文件,检查拍摄时间及相机厂商
+func assertRawCaptureTime(t *testing.T, path, wantMake string) {
+	t.Helper()
+	metadata, err := (&exifMetadataProvider{}).Read(context.Background(), path)
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if !metadata.CaptureTime.Equal(testRawCaptureTime) || !metadata.OffsetKnown {
+		t.Errorf("CaptureTime = %v (OffsetKnown %v), want %v", metadata.CaptureTime, metadata.OffsetKnown, testRawCaptureTime)
+	}
+	if _, offset := metadata.CaptureTime.Zone(); offset != 9*3600 {
+		t.Errorf("CaptureTime offset = %d, want %d", offset, 9*3600)
+	}
+	if metadata.Make != wantMake {
+		t.Errorf("Make = %q, want %q", metadata.Make, wantMake)
+	}
+}
+
+func TestTIFFRawCaptureTime(t *testing.T) {
+	le, be := binary.ByteOrder(binary.LittleEndian), binary.ByteOrder(binary.BigEndian)
+	camera := func(cameraMake string) map[uint16]string {
+		return map[uint16]string{0x010f: cameraMake, 0x0110: "Test Model", 0x0132: "2024:01:01 00:00:00"}
+	}
+	tests := []struct {
+		ext  string
+		data []byte
+		make string
+	}{
+		{".CR2", buildTIFF(le, 42, camera("Canon"), testRawExif), "Canon"},
+		{".NEF", buildTIFF(be, 42, camera("NIKON CORPORATION"), testRawExif), "NIKON CORPORATION"},
+		{".NRW", buildTIFF(be, 42, camera("NIKON CORPORATION"), testRawExif), "NIKON CORPORATION"},
+		{".ARW", buildTIFF(le, 42, camera("SONY"), testRawExif), "SONY"},
+		{".SR2", buildTIFF(le, 42, camera("SONY"), testRawExif), "SONY"},
+		{".DNG", buildTIFF(le, 42, camera("Leica Camera AG"), testRawExif), "Leica Camera AG"},
+		{".ORF", buildTIFF(le, 0x4f52, camera("OLYMPUS CORPORATION"), testRawExif), "OLYMPUS CORPORATION"},
+		{".PEF", buildTIFF(be, 42, camera("PENTAX"), testRawExif), "PENTAX"},
+		{".SRW", buildTIFF(le, 42, camera("SAMSUNG"), testRawExif), "SAMSUNG"},
+		{".3FR", buildTIFF(le, 42, camera("Hasselblad"), testRawExif), "Hasselblad"},
+		{".IIQ", buildTIFF(le, 42, camera("Phase One"), testRawExif), "Phase One"},
+		// RW2的IFD0中没有拍摄时间,保存在内嵌的JPEG预览中
+		{".RW2", append(buildTIFF(le, 0x55, camera("Panasonic"), nil), buildJPEG(buildTIFF(le, 42, camera("Panasonic"), testRawExif))...), "Panasonic"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ext, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "IMG_0001"+tt.ext)
+			if err := os.WriteFile(path, tt.data, 0644); err != nil {
+				t.Fatal(err)
+			}
+			assertRawCaptureTime(t, path, tt.make)
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	_ "time/tzdata" // 内置时区数据,没有安装时区数据库的系统(如Windows)也能使用时区名称
+)
+
+// 时区处理方式
+const (
+	TimeZoneLocal    = "local"    // 转换为本地时区
+	TimeZoneOriginal = "original" // 保留拍摄时的时区
+)
+
+// DefaultTimeZone 默认的时区处理方式
+const DefaultTimeZone = TimeZoneLocal
+
+// TimeZoneTextMap 时区处理方式说明
+var TimeZoneTextMap = map[string]string{
+	TimeZoneLocal:    "转换为本地时区,与同时拍摄的照片一致",
+	TimeZoneOriginal: "保留拍摄时的时区(如EXIF中的OffsetTimeOriginal、QuickTime拍摄时间中的时区),只有UTC时间时转换为本地时区",
+}
+
+// TimeZonePolicy 时区处理方式,将各来源的时间统一到同一时区后再生成文件名
+type TimeZonePolicy struct {
+	Name     string         // 处理方式,local、original或时区名称
+	Location *time.Location // 转换到的时区,保留原时区时为nil
+}
+
+// ParseTimeZonePolicy 解析时区处理方式,支持local、original、时区名称(如Asia/Shanghai、UTC)及固定偏移(如+08:00)
+func ParseTimeZonePolicy(value string) (*TimeZonePolicy, error) {
+	switch value {
+	case "", TimeZoneLocal:
+		return &TimeZonePolicy{Name: TimeZoneLocal, Location: time.Local}, nil
+	case TimeZoneOriginal:
+		return &TimeZonePolicy{Name: TimeZoneOriginal}, nil
+	}
+	if strings.HasPrefix(value, "+") || strings.HasPrefix(value, "-") {
+		for _, layout := range []string{"-07:00", "-0700", "-07"} {
+			if t, err := time.Parse(layout, value); err == nil {
+				_, seconds := t.Zone()
+				return &TimeZonePolicy{Name: value, Location: time.FixedZone(value, seconds)}, nil
+			}
+		}
+		return nil, fmt.Errorf("时区偏移%s格式错误,正确格式如+08:00", value)
+	}
+	location, err := time.LoadLocation(value)
+	if err != nil {
+		return nil, fmt.Errorf("时区%s不存在,可使用local、original、时区名称(如Asia/Shanghai)或偏移(如+08:00)", value)
+	}
+	return &TimeZonePolicy{Name: value, Location: location}, nil
+}
+
+// String 处理方式的说明,用于操作确认
+func (p *TimeZonePolicy) String() string {
+	switch p.Name {
+	case TimeZoneLocal:
+		return fmt.Sprintf("%s(%s)", TimeZoneTextMap[TimeZoneLocal], time.Local)
+	case TimeZoneOriginal:
+		return TimeZoneTextMap[TimeZoneOriginal]
+	}
+	return "转换为" + p.Name
+}
+
+// Normalize 将时间转换到统一的时区
+// 没有时区信息的时间为拍摄地的当地时间,无法换算,保留原时间
+func (p *TimeZonePolicy) Normalize(date *MetadataDate) time.Time {
+	t := date.Time
+	if !date.OffsetKnown {
+		if p.Location == nil {
+			return t
+		}
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), p.Location)
+	}
+	if p.Location != nil {
+		return t.In(p.Location)
+	}
+	// 只有UTC时间的来源(如mvhd、Matroska DateUTC)没有记录拍摄时的时区
+	if t.Location() == time.UTC {
+		return t.In(time.Local)
+	}
+	return t
+}
@@ -0,0 +1,180 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// UndoResult 撤销结果统计
+type UndoResult struct {
+	Restored  int      // 成功恢复的文件数量
+	Skipped   []string // 无法恢复的文件及原因
+	Remaining int      // 收到中断信号后未处理的记录数量
+}
+
+// ReadUndoEntries 读取指定运行ID中需要撤销的文件操作记录
+func ReadUndoEntries(journalPath, runID string) ([]*JournalEntry, error) {
+	entries, err := readJournal(journalPath, func(entry *JournalEntry) bool {
+		return entry.RunID == runID && (entry.IsFileOp() || entry.Pending)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if entries = reconcilePending(entries); len(entries) == 0 {
+		return nil, fmt.Errorf("日志中不存在运行ID为%s的文件操作记录", runID)
+	}
+	return entries, nil
+}
+
+// Undo 按日志倒序撤销文件操作,撤销操作本身也会写入journal,收到中断信号时停止撤销后续文件
+func Undo(ctx context.Context, entries []*JournalEntry, force bool, journal *Journal) *UndoResult {
+	// 去重合并时多个文件会覆盖到同一路径,最后一个之前的文件需复制恢复
+	pending := make(map[string]int)
+	for _, entry := range entries {
+		pending[entry.NewPath]++
+	}
+	result := &UndoResult{}
+	for i := len(entries) - 1; i >= 0 && ctx.Err() == nil; i-- {
+		entry := entries[i]
+		pending[entry.NewPath]--
+		if entry.Op == JournalOpDelete {
+			if reason := restoreEntry(entry, journal); reason != "" {
+				result.Skipped = append(result.Skipped, fmt.Sprintf("%s: %s", entry.OldPath, reason))
+				continue
+			}
+			result.Restored++
+			continue
+		}
+		if reason := undoEntry(context.WithoutCancel(ctx), entry, pending[entry.NewPath] > 0, force, journal); reason != "" {
+			result.Skipped = append(result.Skipped, fmt.Sprintf("%s: %s", entry.NewPath, reason))
+			continue
+		}
+		result.Restored++
+	}
+	if ctx.Err() != nil {
+		result.Remaining = len(entries) - result.Restored - len(result.Skipped)
+	}
+	return result
+}
+
+// undoEntry 撤销单条日志记录,返回无法撤销的原因
+func undoEntry(ctx context.Context, entry *JournalEntry, keepTarget, force bool, journal *Journal) string {
+	if _, err := os.Stat(entry.NewPath); os.IsNotExist(err) {
+		return "文件已被移动或删除"
+	}
+	matched, err := entry.MatchesFile(ctx, entry.NewPath)
+	if err != nil {
+		return err.Error()
+	}
+	if !matched && !force {
+		return "文件内容已被修改,可使用--force强制恢复"
+	}
+	// 复制操作的撤销即删除复制出的文件
+	if entry.Op == JournalOpCopy {
+		if err = os.Remove(entry.NewPath); err != nil {
+			return err.Error()
+		}
+		removeEmptyUnknownDateDir(entry.NewPath)
+		return ""
+	}
+	if _, err = os.Stat(entry.OldPath); !os.IsNotExist(err) {
+		return fmt.Sprintf("原路径%s已被占用", entry.OldPath)
+	}
+	if err = os.MkdirAll(filepath.Dir(entry.OldPath), 0755); err != nil {
+		return err.Error()
+	}
+	op := JournalOpMove
+	if keepTarget {
+		op = JournalOpCopy
+	}
+	if err = journal.Pending(op, entry.NewPath, entry.OldPath); err != nil {
+		return err.Error()
+	}
+	if keepTarget {
+		err = copyFile(entry.NewPath, entry.OldPath)
+	} else {
+		err = moveExclusive(entry.NewPath, entry.OldPath)
+	}
+	if errors.Is(err, os.ErrExist) {
+		return fmt.Sprintf("原路径%s已被占用", entry.OldPath)
+	}
+	if err != nil {
+		return err.Error()
+	}
+	if !keepTarget {
+		removeEmptyUnknownDateDir(entry.NewPath)
+	}
+	if err = journal.Append(op, entry.NewPath, entry.OldPath); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+// restoreEntry 按日志中记录的内容恢复被删除的附属文件,恢复的文件记录为复制,再次撤销时将其删除
+func restoreEntry(entry *JournalEntry, journal *Journal) string {
+	if _, err := os.Stat(entry.OldPath); !os.IsNotExist(err) {
+		return fmt.Sprintf("原路径%s已被占用", entry.OldPath)
+	}
+	if err := os.MkdirAll(filepath.Dir(entry.OldPath), 0755); err != nil {
+		return err.Error()
+	}
+	if err := writeFileExclusive(entry.OldPath, entry.Data); err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return fmt.Sprintf("原路径%s已被占用", entry.OldPath)
+		}
+		return err.Error()
+	}
+	if err := journal.Append(JournalOpCopy, "", entry.OldPath); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+// moveExclusive 移动文件,目标文件已存在时返回os.ErrExist,避免检查之后出现的文件被覆盖
+func moveExclusive(oldPath, newPath string) error {
+	err := os.Link(oldPath, newPath)
+	if err == nil {
+		return os.Remove(oldPath)
+	}
+	if errors.Is(err, os.ErrExist) {
+		return err
+	}
+	// 跨文件系统或不支持硬链接时复制后删除原文件
+	if err = copyFile(oldPath, newPath); err != nil {
+		if !errors.Is(err, os.ErrExist) {
+			_ = os.Remove(newPath)
+		}
+		return err
+	}
+	return os.Remove(oldPath)
+}
+
+// writeFileExclusive 写入新文件并同步到磁盘,文件已存在时返回os.ErrExist
+func writeFileExclusive(path string, data []byte) error {
+	out, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err = out.Write(data); err == nil {
+		err = out.Sync()
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(path)
+	}
+	return err
+}
+
+// removeEmptyUnknownDateDir 文件移出后unknown-date文件夹为空时将其删除
+func removeEmptyUnknownDateDir(path string) {
+	dir := filepath.Dir(path)
+	if filepath.Base(dir) == UnknownDateDir {
+		// 文件夹非空时删除失败,无需处理
+		_ = os.Remove(dir)
+	}
+}
@@ -0,0 +1,168 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// undoTestEntry 创建移动后的文件并返回对应的日志记录
+func undoTestEntry(t *testing.T, dir string) *JournalEntry {
+	t.Helper()
+	newPath := filepath.Join(dir, "IMG_20240501_120000.JPG")
+	if err := os.WriteFile(newPath, []byte("photo"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	hash, err := GetFileHash(context.Background(), newPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &JournalEntry{Op: JournalOpMove, OldPath: filepath.Join(dir, "photo.jpg"), NewPath: newPath, Hash: hash}
+}
+
+// openTestJournal 在临时目录中打开日志
+func openTestJournal(t *testing.T) *Journal {
+	t.Helper()
+	journal, err := OpenJournal(filepath.Join(t.TempDir(), JournalFileName), NewRunID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { journal.Close() })
+	return journal
+}
+
+func TestUndo(t *testing.T) {
+	tests := []struct {
+		name        string
+		setup       func(t *testing.T, entry *JournalEntry) // 撤销前修改磁盘上的文件
+		wantSkipped string                                  // 未恢复的原因,为空时应恢复成功
+		wantOld     string                                  // 撤销后原路径的内容
+	}{
+		{"恢复移动的文件", func(t *testing.T, entry *JournalEntry) {}, "", "photo"},
+		{"文件已被删除", func(t *testing.T, entry *JournalEntry) {
+			if err := os.Remove(entry.NewPath); err != nil {
+				t.Fatal(err)
+			}
+		}, "文件已被移动或删除", ""},
+		{"原路径已被占用", func(t *testing.T, entry *JournalEntry) {
+			if err := os.WriteFile(entry.OldPath, []byte("other"), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}, "已被占用", "other"},
+		{"文件内容已被修改", func(t *testing.T, entry *JournalEntry) {
+			if err := os.WriteFile(entry.NewPath, []byte("edited"), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}, "文件内容已被修改", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry := undoTestEntry(t, t.TempDir())
+			tt.setup(t, entry)
+			result := Undo(context.Background(), []*JournalEntry{entry}, false, openTestJournal(t))
+			if tt.wantSkipped == "" {
+				if result.Restored != 1 || len(result.Skipped) != 0 {
+					t.Fatalf("Undo() = %+v, want 1 restored", result)
+				}
+				if _, err := os.Stat(entry.NewPath); !os.IsNotExist(err) {
+					t.Errorf("%s still exists after undo", entry.NewPath)
+				}
+			} else if result.Restored != 0 || len(result.Skipped) != 1 || !strings.Contains(result.Skipped[0], tt.wantSkipped) {
+				t.Fatalf("Undo() = %+v, want skipped with %q", result, tt.wantSkipped)
+			}
+			data, _ := os.ReadFile(entry.OldPath)
+			if string(data) != tt.wantOld {
+				t.Errorf("%s = %q, want %q", entry.OldPath, data, tt.wantOld)
+			}
+		})
+	}
+}
+
+func TestUndoRestoreDeleted(t *testing.T) {
+	dir := t.TempDir()
+	entry := &JournalEntry{Op: JournalOpDelete, OldPath: filepath.Join(dir, "photo.jpg.json"), Data: []byte(`{"title":"photo.jpg"}`)}
+	result := Undo(context.Background(), []*JournalEntry{entry}, false, openTestJournal(t))
+	if data, _ := os.ReadFile(entry.OldPath); result.Restored != 1 || string(data) != string(entry.Data) {
+		t.Fatalf("Undo() = %+v, restored content %q", result, data)
+	}
+	// 再次恢复时原路径已存在,不应覆盖
+	if err := os.WriteFile(entry.OldPath, []byte("other"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	result = Undo(context.Background(), []*JournalEntry{entry}, false, openTestJournal(t))
+	if len(result.Skipped) != 1 || !strings.Contains(result.Skipped[0], "已被占用") {
+		t.Errorf("Undo() = %+v, want skipped with occupied path", result)
+	}
+	if data, _ := os.ReadFile(entry.OldPath); string(data) != "other" {
+		t.Errorf("%s = %q, want it untouched", entry.OldPath, data)
+	}
+}
+
+func TestUndoInterrupted(t *testing.T) {
+	entry := undoTestEntry(t, t.TempDir())
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	result := Undo(ctx, []*JournalEntry{entry}, false, openTestJournal(t))
+	if result.Restored != 0 || len(result.Skipped) != 0 || result.Remaining != 1 {
+		t.Errorf("Undo() = %+v, want 1 remaining", result)
+	}
+}
+
+func TestMoveExclusive(t *testing.T) {
+	dir := t.TempDir()
+	oldPath, newPath := filepath.Join(dir, "a.jpg"), filepath.Join(dir, "b.jpg")
+	for path, data := range map[string]string{oldPath: "a", newPath: "b"} {
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := moveExclusive(oldPath, newPath); !errors.Is(err, os.ErrExist) {
+		t.Fatalf("moveExclusive() error = %v, want os.ErrExist", err)
+	}
+	if data, _ := os.ReadFile(newPath); string(data) != "b" {
+		t.Errorf("%s = %q, want it untouched", newPath, data)
+	}
+	if err := os.Remove(newPath); err != nil {
+		t.Fatal(err)
+	}
+	if err := moveExclusive(oldPath, newPath); err != nil {
+		t.Fatalf("moveExclusive() error = %v", err)
+	}
+	if _, err := os.Stat(oldPath); !os.IsNotExist(err) {
+		t.Errorf("%s still exists after move", oldPath)
+	}
+}
+
+func TestUndoJournalOperatorMove(t *testing.T) {
+	dir := t.TempDir()
+	oldPath, newPath := filepath.Join(dir, "photo.jpg"), filepath.Join(dir, "IMG_20240501_120000.JPG")
+	if err := os.WriteFile(oldPath, []byte("photo"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	journalPath := filepath.Join(dir, JournalFileName)
+	journal, err := OpenJournal(journalPath, NewRunID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer journal.Close()
+	if _, err = NewJournalOperator(NewDiskOperator(), journal).Move(oldPath, newPath); err != nil {
+		t.Fatalf("Move() error = %v", err)
+	}
+	entries, err := ReadUndoEntries(journalPath, journal.RunID)
+	if err != nil {
+		t.Fatalf("ReadUndoEntries() error = %v", err)
+	}
+	// 移动后的文件只记录大小及修改时间,不计算md5
+	if len(entries) != 1 || entries[0].Hash != "" || entries[0].ModTime == "" || entries[0].Size != 5 {
+		t.Fatalf("ReadUndoEntries() = %+v, want one move with size and mod time", entries)
+	}
+	if result := Undo(context.Background(), entries, false, openTestJournal(t)); result.Restored != 1 {
+		t.Fatalf("Undo() = %+v, want 1 restored", result)
+	}
+	if data, _ := os.ReadFile(oldPath); string(data) != "photo" {
+		t.Errorf("%s = %q, want restored content", oldPath, data)
+	}
+}
@@ -0,0 +1,62 @@
+package core
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+
+	"github.com/thoas/go-funk"
+)
+
+// errNotWebP 文件不是WebP格式
+var errNotWebP = errors.New("不是有效的WEBP文件")
+
+// IsWebP 判断文件是否为WebP图片
+func IsWebP(path string) bool {
+	return funk.ContainsString([]string{".WEBP"}, GetExt(path))
+}
+
+// webpMetadata WebP RIFF数据块中的元数据
+type webpMetadata struct {
+	exif []byte // EXIF块,以TIFF头开始
+	xmp  []byte // XMP块
+}
+
+// readWebPMetadata 读取WebP的EXIF及XMP数据块,跳过图像数据
+func readWebPMetadata(filename string) (*webpMetadata, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	header := make([]byte, 12)
+	if _, err = io.ReadFull(file, header); err != nil || string(header[:4]) != "RIFF" || string(header[8:12]) != "WEBP" {
+		return nil, errNotWebP
+	}
+	end := 8 + int64(binary.LittleEndian.Uint32(header[4:8]))
+	metadata := &webpMetadata{}
+	for offset := int64(12); offset+8 <= end; {
+		// 数据块: 类型(4) + 长度(4,小端) + 数据,长度为奇数时补1字节
+		if _, err = file.ReadAt(header[:8], offset); err != nil {
+			return metadata, nil
+		}
+		chunkType := string(header[:4])
+		length := int64(binary.LittleEndian.Uint32(header[4:8]))
+		if (chunkType == "EXIF" || chunkType == "XMP ") && length <= metadataMaxChunkSize {
+			data := make([]byte, length)
+			if _, err = file.ReadAt(data, offset+8); err != nil {
+				return metadata, nil
+			}
+			if chunkType == "EXIF" {
+				// 部分软件在TIFF头前写入了Exif\0\0
+				metadata.exif = bytes.TrimPrefix(data, []byte("Exif\x00\x00"))
+			} else {
+				metadata.xmp = data
+			}
+		}
+		offset += 8 + length + length%2
+	}
+	return metadata, nil
+}
@@ -0,0 +1,76 @@
+package core
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// webpTestChunk 构造RIFF数据块,长度为奇数时补1字节
+func webpTestChunk(chunkType string, data []byte) []byte {
+	chunk := binary.LittleEndian.AppendUint32([]byte(chunkType), uint32(len(data)))
+	chunk = append(chunk, data...)
+	if len(data)%2 == 1 {
+		chunk = append(chunk, 0)
+	}
+	return chunk
+}
+
+// buildWebP 构造扩展格式(VP8X)的WebP,元数据块位于图像数据之后
+func buildWebP(chunks ...[]byte) []byte {
+	body := append([]byte("WEBP"), webpTestChunk("VP8X", []byte{0x0c, 0, 0, 0, 0, 0, 0, 0, 0, 0})...)
+	body = append(body, webpTestChunk("VP8L", []byte{0x2f, 0, 0, 0, 0})...)
+	for _, chunk := range chunks {
+		body = append(body, chunk...)
+	}
+	return append(binary.LittleEndian.AppendUint32([]byte("RIFF"), uint32(len(body))), body...)
+}
+
+func TestReadWebPMetadata(t *testing.T) {
+	exif := buildTIFF(binary.LittleEndian, 42, nil, testRawExif)
+	// 长度为奇数的XMP块之后需跳过补齐的字节才能读到EXIF块
+	oddXMP := append(append([]byte{}, testXMP...), ' ')
+	if len(oddXMP)%2 == 0 {
+		oddXMP = append(oddXMP, ' ')
+	}
+	tests := []struct {
+		name     string
+		data     []byte
+		wantExif []byte
+		wantXMP  []byte
+	}{
+		{"EXIF块", buildWebP(webpTestChunk("EXIF", exif)), exif, nil},
+		{"带有Exif前缀的EXIF块", buildWebP(webpTestChunk("EXIF", append([]byte("Exif\x00\x00"), exif...))), exif, nil},
+		{"长度为奇数的块之后的EXIF块", buildWebP(webpTestChunk("XMP ", oddXMP), webpTestChunk("EXIF", exif)), exif, oddXMP},
+		{"超出长度上限的块", buildWebP(binary.LittleEndian.AppendUint32([]byte("EXIF"), metadataMaxChunkSize+1)), nil, nil},
+		{"没有元数据", buildWebP(), nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "image.webp")
+			if err := os.WriteFile(path, tt.data, 0644); err != nil {
+				t.Fatal(err)
+			}
+			metadata, err := readWebPMetadata(path)
+			if err != nil {
+				t.Fatalf("readWebPMetadata() error = %v", err)
+			}
+			if !bytes.Equal(metadata.exif, tt.wantExif) || !bytes.Equal(metadata.xmp, tt.wantXMP) {
+				t.Errorf("exif = %x, xmp = %q, want %x, %q", metadata.exif, metadata.xmp, tt.wantExif, tt.wantXMP)
+			}
+		})
+	}
+}
+
+func TestReadWebPMetadataNotWebP(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "image.webp")
+	if err := os.WriteFile(path, []byte("RIFF\x04\x00\x00\x00WAVE"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readWebPMetadata(path); !errors.Is(err, errNotWebP) {
+		t.Errorf("readWebPMetadata() error = %v, want %v", err, errNotWebP)
+	}
+}
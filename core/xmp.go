@@ -0,0 +1,160 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// xmpDateTags XMP中的时间标签,按优先级排列
+var xmpDateTags = []string{"exif:DateTimeOriginal", "photoshop:DateCreated", "xmp:CreateDate"}
+
+// XMP中的相机标签
+const (
+	xmpMakeTag  = "tiff:Make"  // 相机厂商
+	xmpModelTag = "tiff:Model" // 相机型号
+)
+
+// xmpValuePatterns 读取的XMP标签及其值的匹配模式
+var xmpValuePatterns = compileXMPValuePatterns(append([]string{xmpMakeTag, xmpModelTag}, xmpDateTags...))
+
+// HasEmbeddedXMP 判断是否支持读取该格式图片内嵌的XMP
+func HasEmbeddedXMP(path string) bool {
+	return IsJPEG(path) || IsPNG(path) || IsWebP(path) || IsGIF(path) || IsHEIF(path) || IsCR3(path) || IsTIFFRaw(path)
+}
+
+// ReadEmbeddedXMP 按文件格式读取图片内嵌的XMP,没有时返回nil
+func ReadEmbeddedXMP(path string) ([]byte, error) {
+	switch {
+	case IsJPEG(path):
+		return readJPEGXMP(path)
+	case IsPNG(path):
+		png, err := readPNGMetadata(path)
+		if err != nil {
+			return nil, err
+		}
+		return png.xmp, nil
+	case IsWebP(path):
+		webp, err := readWebPMetadata(path)
+		if err != nil {
+			return nil, err
+		}
+		return webp.xmp, nil
+	case IsGIF(path):
+		return readGIFXMP(path)
+	case IsHEIF(path):
+		return GetHEIFXMP(path)
+	case IsCR3(path):
+		return GetCR3XMP(path)
+	case IsTIFFRaw(path):
+		return GetTIFFXMP(path)
+	}
+	return nil, nil
+}
+
+// ReadSidecarXMP 读取文件的XMP附属文件,没有附属文件或读取失败时返回nil
+func ReadSidecarXMP(path string) []byte {
+	sidecar := FindSidecar(path)
+	if sidecar == "" {
+		return nil
+	}
+	data, err := os.ReadFile(sidecar)
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+// GetXMPCamera 读取XMP附属文件或内嵌XMP中的相机厂商及型号,附属文件优先
+func GetXMPCamera(path string) (cameraMake, cameraModel string) {
+	if data := ReadSidecarXMP(path); data != nil {
+		if cameraMake, cameraModel = findXMPValue(data, xmpMakeTag), findXMPValue(data, xmpModelTag); cameraMake != "" || cameraModel != "" {
+			return cameraMake, cameraModel
+		}
+	}
+	if !HasEmbeddedXMP(path) {
+		return "", ""
+	}
+	data, err := ReadEmbeddedXMP(path)
+	if err != nil {
+		return "", ""
+	}
+	return findXMPValue(data, xmpMakeTag), findXMPValue(data, xmpModelTag)
+}
+
+// xmpMetadata 由XMP生成元数据,包含拍摄时间及相机,没有XMP时返回空元数据
+func xmpMetadata(xmp []byte) *Metadata {
+	metadata := &Metadata{Make: findXMPValue(xmp, xmpMakeTag), Model: findXMPValue(xmp, xmpModelTag)}
+	if date := ParseXMPDate(xmp); date != nil {
+		metadata.setDate(DateSourceXMP, date.Time, date.OffsetKnown)
+	}
+	return metadata
+}
+
+// FindSidecar 查找文件的XMP附属文件,支持photo.xmp及photo.jpg.xmp两种命名,不区分扩展名大小写,不存在时返回空
+func FindSidecar(path string) string {
+	base := strings.TrimSuffix(path, filepath.Ext(path))
+	for _, candidate := range []string{path, base} {
+		for _, ext := range []string{".xmp", ".XMP"} {
+			if info, err := os.Stat(candidate + ext); err == nil && !info.IsDir() {
+				return candidate + ext
+			}
+		}
+	}
+	return ""
+}
+
+// xmpPacketPattern 内嵌的XMP数据包,部分软件省略xpacket包装,只有x:xmpmeta
+var xmpPacketPattern = regexp.MustCompile(`(?s)<\?xpacket begin=.*?<\?xpacket end=[^>]*>|<x:xmpmeta[\s>].*?</x:xmpmeta>`)
+
+// FindXMPPacket 在图片的数据块中查找XMP数据包,没有时返回nil
+func FindXMPPacket(data []byte) []byte {
+	return xmpPacketPattern.Find(data)
+}
+
+// ParseXMPDate 读取XMP中的拍摄时间,标签可以是属性或元素形式,没有时返回nil
+func ParseXMPDate(data []byte) *MetadataDate {
+	for _, tag := range xmpDateTags {
+		value := findXMPValue(data, tag)
+		if value == "" {
+			continue
+		}
+		if date := parseXMPDateValue(value); date != nil {
+			return date
+		}
+	}
+	return nil
+}
+
+// compileXMPValuePatterns 编译标签值的匹配模式,标签可以是属性或元素形式
+func compileXMPValuePatterns(tags []string) map[string]*regexp.Regexp {
+	patterns := make(map[string]*regexp.Regexp, len(tags))
+	for _, tag := range tags {
+		quoted := regexp.QuoteMeta(tag)
+		patterns[tag] = regexp.MustCompile(`\b` + quoted + `\s*=\s*["']([^"']*)["']|<` + quoted + `>\s*([^<]*?)\s*</` + quoted + `>`)
+	}
+	return patterns
+}
+
+// findXMPValue 查找XMP标签的值,如exif:DateTimeOriginal="..."或<exif:DateTimeOriginal>...</exif:DateTimeOriginal>,标签须在xmpValuePatterns中
+func findXMPValue(data []byte, tag string) string {
+	matches := xmpValuePatterns[tag].FindSubmatch(data)
+	if matches == nil {
+		return ""
+	}
+	if len(matches[1]) > 0 {
+		return string(matches[1])
+	}
+	return string(matches[2])
+}
+
+// parseXMPDateValue 解析XMP时间,如2025-06-06T12:16:01.045+08:00,没有时区时按本地时区保存
+func parseXMPDateValue(value string) *MetadataDate {
+	date, err := ParseDateTime(value, time.Local)
+	if err != nil {
+		return nil
+	}
+	return date
+}
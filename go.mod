@@ -4,13 +4,13 @@ go 1.24
 
 require (
 	github.com/djherbis/times v1.6.0
-	github.com/dromara/carbon/v2 v2.6.7
 	github.com/dsoprea/go-exif/v3 v3.0.1
 	github.com/fatih/color v1.18.0
 	github.com/spf13/cobra v1.9.1
 	github.com/thoas/go-funk v0.9.3
 	github.com/tidwall/gjson v1.18.0
 	github.com/vbauerster/mpb/v8 v8.10.1
+	gopkg.in/yaml.v2 v2.4.0
 )
 
 require (
@@ -32,5 +32,4 @@ require (
 	golang.org/x/net v0.0.0-20221002022538-bcab6841153b // indirect
 	golang.org/x/sys v0.33.0 // indirect
 	gopkg.in/check.v1 v1.0.0-20180628173108-788fd7840127 // indirect
-	gopkg.in/yaml.v2 v2.4.0 // indirect
 )